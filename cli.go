@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -31,8 +32,17 @@ var DefaultFilenameCompletion = cli.DefaultFilenameCompletion
 // as a fallback.
 var MatchingFilenameCompletion = cli.MatchingFilenameCompletion
 
+// Exit returns an error that terminates the process with a specific exit code,
+// after printing the error `err`, if not nil. It is meant to be returned by
+// command handlers.
+var Exit = cli.Exit
+
 // Run takes the command line arguments, parses them and execute the
-// command or sub-command with the corresponding options.
+// command or sub-command with the corresponding options. Errors are reported
+// prefixed with the name of the command; usage errors along with a usage hint
+// and exit code 2, errors implementing `cli.ExitCoder` with their own exit
+// code, and other errors with exit code 1. Colors are enabled if the output is
+// a terminal, unless `cmd.Color` is already set by the caller.
 func Run(cmd *Command) {
 	if cmd.ProcessName == "" {
 		cmd.ProcessName = fileutils.Base(os.Args[0])
@@ -42,11 +52,47 @@ func Run(cmd *Command) {
 	}
 	cmd.ConsoleWidth = consoleWidth()
 	cmd.SetProcessEnv(os.Environ())
+	var forceColor = cmd.Color
+	if !cmd.Color {
+		cmd.Color = cli.ColorEnabled(cmd.ProcessEnv, term.IsTerminal(int(os.Stdout.Fd())))
+	}
 
 	var err = cmd.Run()
+	var usageErr *cli.UsageError
+	var exitErr cli.ExitCoder
 	if errors.Is(err, cli.ErrCompletionScriptRequested) {
 		fmt.Print(cli.BashCompletionScript(cmd.ProcessName))
 
+	} else if errors.Is(err, cli.ErrZshCompletionScriptRequested) {
+		fmt.Print(cli.ZshCompletionScript(cmd.ProcessName))
+
+	} else if errors.Is(err, cli.ErrFishCompletionScriptRequested) {
+		fmt.Print(cli.FishCompletionScript(cmd.ProcessName))
+
+	} else if errors.Is(err, cli.ErrPowerShellCompletionScriptRequested) {
+		fmt.Print(cli.PowerShellCompletionScript(cmd.ProcessName))
+
+	} else if errors.Is(err, cli.ErrSchemaRequested) {
+		schema, err := cmd.Schema()
+		if err != nil {
+			printError(cmd, forceColor, err)
+			os.Exit(1)
+		}
+		var e = json.NewEncoder(os.Stdout)
+		e.SetIndent("", "    ")
+		e.Encode(schema)
+
+	} else if errors.Is(err, cli.ErrManPageRequested) {
+		page, err := cmd.ManPage()
+		if err != nil {
+			printError(cmd, forceColor, err)
+			os.Exit(1)
+		}
+		fmt.Print(page)
+
+	} else if errors.Is(err, cli.ErrShowConfigRequested) {
+		fmt.Print(cmd.ShowConfig())
+
 	} else if errors.Is(err, cli.ErrHelpRequested) {
 		fmt.Print(cmd.Usage())
 
@@ -59,12 +105,36 @@ func Run(cmd *Command) {
 		for _, v := range cmd.Suggestions {
 			fmt.Println(v)
 		}
+	} else if errors.As(err, &usageErr) {
+		printError(cmd, forceColor, err)
+		fmt.Fprintf(os.Stderr, "%v\n", cmd.UsageHint())
+		os.Exit(usageErr.ExitCode())
+
+	} else if errors.As(err, &exitErr) {
+		if err.Error() != "" {
+			printError(cmd, forceColor, err)
+		}
+		os.Exit(exitErr.ExitCode())
+
 	} else if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		printError(cmd, forceColor, err)
 		os.Exit(1)
 	}
 }
 
+// printError prints `err` to stderr, prefixed with the name of the command. The
+// prefix is styled if colors are enabled for stderr or forced by the caller.
+func printError(cmd *Command, forceColor bool, err error) {
+	var style cli.Style
+	if forceColor || cli.ColorEnabled(cmd.ProcessEnv, term.IsTerminal(int(os.Stderr.Fd()))) {
+		style = cli.DefaultTheme.Error
+		if cmd.Theme != nil {
+			style = cmd.Theme.Error
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%v %v\n", style.Apply(cmd.ProcessName+":"), err)
+}
+
 func consoleWidth() int {
 	var width = 80
 	if ww, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
@@ -6,8 +6,10 @@ require (
 	github.com/maargenton/go-errors v1.0.0
 	github.com/maargenton/go-fileutils v0.6.4
 	github.com/maargenton/go-testpredicate v1.3.0
+	github.com/pelletier/go-toml/v2 v2.0.9
 	golang.org/x/term v0.12.0
 	golang.org/x/tools v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -1,7 +1,12 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/maargenton/go-cli/pkg/option"
@@ -12,20 +17,41 @@ import (
 // ---------------------------------------------------------------------------
 
 // Command is the representation of a runnable command, with reference to a
-// runnable command attached to an options struct
+// runnable command attached to an options struct. A command can define a tree
+// of sub-commands, each with its own handler and options; the options of the
+// parent command are parsed up to the name of the sub-command, and the
+// sub-command parses the remaining arguments. Only the handler of the selected
+// leaf command is run; the handler of a parent command, which can be nil, is
+// run only when no sub-command is specified.
 type Command struct {
-	Handler     Handler
-	Description string
+	Handler          Handler
+	Description      string
+	Subcommands      map[string]*Command
+	ConfigSearchPath []string // configuration files to try if none is specified
+	Examples         string   // optional examples of use, displayed after the options
+	Footer           string   // optional text displayed at the end of the usage
 
-	ProcessName       string
-	ProcessArgs       []string
-	ProcessEnv        map[string]string
-	ConsoleWidth      int
-	DisableCompletion bool
+	ProcessName        string
+	ProcessArgs        []string
+	ProcessEnv         map[string]string
+	ConsoleWidth       int
+	DisableCompletion  bool
+	EnableSchema       bool
+	EnableManPage      bool
+	EnableShowConfig   bool
+	AllowAbbreviations bool   // accept unambiguous prefixes of long flags
+	Color              bool   // style the usage with ANSI escape sequences
+	Theme              *Theme // styles used when Color is set, DefaultTheme if nil
 
 	Suggestions []string
 
-	opts *option.Set
+	opts       *option.Set
+	config     *option.Config
+	parent     *Command
+	selected   *Command
+	argOffset  int
+	showConfig bool
+	configErr  error
 }
 
 // Handler defines the interface necessary to run a command once the command
@@ -46,12 +72,34 @@ type UsageHandler interface {
 	Usage(name string, width int) string
 }
 
+// ValidateHandler is an optional interface for the command handler to check
+// the consistency of its options once all sources have been applied. Errors
+// returned are reported as usage errors.
+type ValidateHandler interface {
+	Validate() error
+}
+
+// PrepareHandler is an optional interface for the command handler to perform
+// any setup work once the options have been validated, before the command is
+// run. Errors returned are reported as runtime errors.
+type PrepareHandler interface {
+	Prepare() error
+}
+
 // CompletionHandler is an optional interface for the command handler to provide
 // meaningful values for a specific option or argument.
 type CompletionHandler interface {
 	Complete(opt *option.T, partial string) []string
 }
 
+// DescriptionCompletionHandler is an optional interface for the command
+// handler to provide meaningful values for a specific option or argument, each
+// with a description that is displayed by shells supporting it. When
+// implemented, it takes precedence over CompletionHandler.
+type DescriptionCompletionHandler interface {
+	CompleteWithDescription(opt *option.T, partial string) []option.Description
+}
+
 // ---------------------------------------------------------------------------
 // Command type public interface
 // ---------------------------------------------------------------------------
@@ -74,56 +122,37 @@ func (cmd *Command) SetProcessEnv(env []string) {
 // Run is the main invocation point for a command. The command must be seeded
 // with all the necessary runtime arguments from the process context
 // (`ProcessName`, `ProcessArgs`, `ProcessEnv` and `ConsoleWidth`). It sets up
-// the command option struct, applies the defaults abd environment variable,
-// decodes the command-line and run the command.
+// the command option struct, applies the defaults, configuration file and
+// environment variable, decodes the command-line, validates the resulting
+// values and run the command. Errors caused by invalid options or arguments are
+// returned as `*UsageError`. If the command defines sub-commands, the process
+// is repeated for the selected sub-command with the remaining arguments.
 func (cmd *Command) Run() error {
-
+	cmd.selected = nil
+	cmd.config = nil
+	cmd.argOffset = 1
+	cmd.showConfig = false
+	cmd.configErr = nil
 	if err := cmd.initialize(); err != nil {
 		return err
 	}
-
-	if _, ok := cmd.Handler.(VersionHandler); ok {
-		cmd.opts.AddSpecialFlag(
-			"v", "version", "display version information",
-			ErrVersionRequested)
-	}
-
-	cmd.opts.AddSpecialFlag(
-		"h", "help", "display usage information",
-		ErrHelpRequested)
+	cmd.addSpecialFlags()
 
 	if !cmd.DisableCompletion {
-		cmd.opts.AddSpecialFlag(
-			"", "bash-completion-script",
-			"generate a bash script that sets up completion for this command; "+
-				"to use, run the following line or add it to your .bash_profile:\n"+
-				"eval $("+cmd.ProcessName+" --bash-completion-script)",
-			ErrCompletionScriptRequested)
-
 		if cmd.handleCompletionRequest() {
 			return ErrCompletionRequested
 		}
 	}
-	if err := cmd.opts.ApplyDefaults(); err != nil {
-		return err
-	}
-	if err := cmd.opts.ApplyEnv(cmd.ProcessEnv); err != nil {
-		return err
-	}
-	if err := cmd.opts.ApplyArgs(cmd.ProcessArgs[1:]); err != nil {
-		return err
-	}
-
-	if err := cmd.Handler.Run(); err != nil {
-		return err
-	}
-
-	return nil
+	return cmd.execute()
 }
 
 // Usage returns a string containign the usage for the command. The display name
-// for the command is expected as first argument.
+// for the command is expected as first argument. If the last call to `Run()`
+// selected a sub-command, the usage of that sub-command is returned instead.
 func (cmd *Command) Usage() string {
+	if cmd.selected != nil {
+		return cmd.selected.Usage()
+	}
 
 	if err := cmd.initialize(); err != nil {
 		return fmt.Sprintf("error initializing the command for Usage:  %v", err)
@@ -133,49 +162,170 @@ func (cmd *Command) Usage() string {
 		return uh.Usage(cmd.ProcessName, cmd.ConsoleWidth)
 	}
 
-	var args []string
-	for _, opt := range cmd.opts.Positional {
-		var name = opt.Name()
-		if opt.Optional {
-			args = append(args, fmt.Sprintf("[%v]", name))
-		} else {
-			args = append(args, fmt.Sprintf("%v", name))
-		}
-	}
-	if cmd.opts.Args != nil {
-		args = append(args, cmd.opts.Args.Name())
-	}
-
+	var theme = cmd.theme()
 	var usage strings.Builder
 	fmt.Fprintf(&usage,
-		"Usage: %v [options] %v\n",
-		cmd.ProcessName, strings.Join(args, " "))
+		"%v %v %v\n",
+		theme.Heading.Apply("Usage:"), cmd.ProcessName, strings.Join(cmd.usageArgs(), " "))
 	fmt.Fprintf(&usage, "%v\n", cmd.Description)
 
-	var options []option.Description
+	var args []option.Description
 	for _, arg := range cmd.opts.Positional {
-		var usage = arg.GetUsage()
+		var usage = arg.GetStyledUsage(theme.Flag.Apply, theme.Value.Apply)
 		if usage.Description != "" {
-			options = append(options, usage)
+			args = append(args, usage)
 		}
 	}
 	if arg := cmd.opts.Args; arg != nil {
-		var usage = arg.GetUsage()
+		var usage = arg.GetStyledUsage(theme.Flag.Apply, theme.Value.Apply)
 		if usage.Description != "" {
-			options = append(options, usage)
+			args = append(args, usage)
 		}
 	}
+	cmd.writeUsageSection(&usage, "Arguments", args)
+
+	// Options are listed by section in order of first appearance, with
+	// special flags last as global options.
+	var sections = []string{""}
+	var options = make(map[string][]option.Description)
+	var global []option.Description
 	for _, opt := range cmd.opts.Options {
-		options = append(options, opt.GetUsage())
+		if opt.Hidden {
+			continue
+		}
+		var u = opt.GetStyledUsage(theme.Flag.Apply, theme.Value.Apply)
+		if opt.Type == option.Special {
+			global = append(global, u)
+			continue
+		}
+		if _, ok := options[opt.Section]; !ok && opt.Section != "" {
+			sections = append(sections, opt.Section)
+		}
+		options[opt.Section] = append(options[opt.Section], u)
+	}
+	for _, section := range sections {
+		var title = section
+		if title == "" {
+			title = "Options"
+		}
+		cmd.writeUsageSection(&usage, title, options[section])
+	}
+	cmd.writeUsageSection(&usage, "Global options", global)
+
+	var groups []option.Description
+	for _, g := range cmd.opts.Groups {
+		var names []string
+		for _, opt := range g.Options {
+			names = append(names, opt.Name())
+		}
+		var d = option.Description{
+			Option:      strings.Join(names, ", "),
+			Description: g.Description(),
+		}
+		if n := len(groups); n > 0 && groups[n-1].Option == d.Option {
+			groups[n-1].Description += ", " + d.Description
+		} else {
+			groups = append(groups, d)
+		}
+	}
+	cmd.writeUsageSection(&usage, "Option groups", groups)
+
+	var commands []option.Description
+	for _, name := range cmd.subcommandNames() {
+		commands = append(commands, option.Description{
+			Option:      name,
+			Description: cmd.Subcommands[name].Description,
+		})
+	}
+	cmd.writeUsageSection(&usage, "Commands", commands)
+
+	if examples := strings.TrimRight(cmd.Examples, "\n"); examples != "" {
+		fmt.Fprintf(&usage, "\n%v\n", theme.Heading.Apply("Examples:"))
+		for _, l := range strings.Split(examples, "\n") {
+			fmt.Fprintf(&usage, "  %v\n", l)
+		}
+	}
+	if footer := strings.TrimSpace(cmd.Footer); footer != "" {
+		fmt.Fprintf(&usage, "\n%v\n", footer)
 	}
-	fmt.Fprint(&usage, option.FormatOptionDescription("  ", cmd.ConsoleWidth, options))
 
 	return usage.String()
 }
 
-// Version returns a version string for the command
+// writeUsageSection writes a titled section of the usage, listing `list`
+// formatted with `option.FormatOptionDescription()`. Empty sections are
+// omitted.
+func (cmd *Command) writeUsageSection(w io.Writer, title string, list []option.Description) {
+	if len(list) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%v\n", cmd.theme().Heading.Apply(title+":"))
+	fmt.Fprint(w, option.FormatOptionDescription("  ", cmd.ConsoleWidth, list))
+}
+
+// UsageHint returns a short message pointing to the usage of the command, or of
+// the sub-command selected by the last call to `Run()`, to be displayed along
+// with usage errors.
+func (cmd *Command) UsageHint() string {
+	if cmd.selected != nil {
+		return cmd.selected.UsageHint()
+	}
+	return fmt.Sprintf("Run '%v --help' for usage.", cmd.ProcessName)
+}
+
+// ShowConfig returns a description of the effective configuration of the
+// command after the last call to `Run()`, listing the value of every option of
+// the command and of the selected sub-commands, along with the source of the
+// value.
+func (cmd *Command) ShowConfig() string {
+	var b strings.Builder
+	for c := cmd; c != nil; c = c.selected {
+		if c.opts == nil {
+			continue
+		}
+		var all = append([]*option.T{}, c.opts.Options...)
+		all = append(all, c.opts.Positional...)
+		if c.opts.Args != nil {
+			all = append(all, c.opts.Args)
+		}
+
+		var names, values []string
+		var w = 0
+		for _, opt := range all {
+			if opt.Type == option.Special || opt.Hidden {
+				continue
+			}
+			var source = opt.Source()
+			if source.Kind == option.ArgSource {
+				source.Index += c.argOffset
+			}
+			var value = opt.FormatValue()
+			if value == "" {
+				value = `""`
+			}
+			names = append(names, opt.Name())
+			values = append(values, fmt.Sprintf("%v (%v)", value, source))
+			if len(opt.Name()) > w {
+				w = len(opt.Name())
+			}
+		}
+		if len(names) != 0 {
+			fmt.Fprintf(&b, "%v:\n", c.ProcessName)
+			for i := range names {
+				fmt.Fprintf(&b, "  %*v : %v\n", -w, names[i], values[i])
+			}
+		}
+	}
+	return b.String()
+}
+
+// Version returns a version string for the command. Sub-commands that do not
+// define their own version inherit the version of their parent command.
 func (cmd *Command) Version() (version string) {
-	if vh, ok := cmd.Handler.(VersionHandler); ok {
+	if cmd.selected != nil {
+		return cmd.selected.Version()
+	}
+	if vh := cmd.versionHandler(); vh != nil {
 		version = vh.Version()
 	}
 	return
@@ -185,15 +335,343 @@ func (cmd *Command) Version() (version string) {
 // Command type private implementation
 // ---------------------------------------------------------------------------
 
+// usageArgs returns the list of arguments of the command as displayed in the
+// usage line, starting with the options.
+func (cmd *Command) usageArgs() []string {
+	var args = []string{"[options]"}
+	for _, opt := range cmd.opts.Positional {
+		var name = opt.Name()
+		if opt.Optional {
+			args = append(args, fmt.Sprintf("[%v]", name))
+		} else {
+			args = append(args, fmt.Sprintf("%v", name))
+		}
+	}
+	if cmd.opts.Args != nil {
+		args = append(args, cmd.opts.Args.Name())
+	}
+	if len(cmd.Subcommands) != 0 {
+		args = append(args, "<command> [<args>]")
+	}
+	return args
+}
+
 // initialize parses the tags of the handler struct and records all the
 // available options. The function is safe to call more than once.
 func (cmd *Command) initialize() error {
 	if cmd.opts == nil {
-		var opts, err = option.NewOptionSet(cmd.Handler)
+		var handler interface{} = cmd.Handler
+		if handler == nil {
+			if len(cmd.Subcommands) == 0 {
+				return fmt.Errorf("command defines neither handler nor sub-commands")
+			}
+			handler = &struct{}{}
+		}
+
+		var opts, err = option.NewOptionSet(handler)
 		if err != nil {
 			return err
 		}
+		if len(cmd.Subcommands) != 0 && (len(opts.Positional) != 0 || opts.Args != nil) {
+			return fmt.Errorf(
+				"command with sub-commands cannot capture positional arguments")
+		}
 		cmd.opts = opts
 	}
+	cmd.opts.AllowAbbreviations = cmd.AllowAbbreviations
+	return nil
+}
+
+// addSpecialFlags adds the built-in special flags to the command option set.
+// The completion script flag is only defined on the root command.
+func (cmd *Command) addSpecialFlags() {
+	if cmd.versionHandler() != nil {
+		cmd.opts.AddSpecialFlag(
+			"v", "version", "display version information",
+			ErrVersionRequested)
+	}
+
+	cmd.opts.AddSpecialFlag(
+		"h", "help", "display usage information",
+		ErrHelpRequested)
+
+	if !cmd.DisableCompletion && cmd.parent == nil {
+		cmd.opts.AddSpecialFlag(
+			"", "bash-completion-script",
+			"generate a bash script that sets up completion for this command; "+
+				"to use, run the following line or add it to your .bash_profile:\n"+
+				"eval $("+cmd.ProcessName+" --bash-completion-script)",
+			ErrCompletionScriptRequested)
+		cmd.opts.AddSpecialFlag(
+			"", "zsh-completion-script",
+			"generate a zsh script that sets up completion for this command; "+
+				"to use, run the following line or add it to your .zshrc after compinit:\n"+
+				"source <("+cmd.ProcessName+" --zsh-completion-script)",
+			ErrZshCompletionScriptRequested)
+		cmd.opts.AddSpecialFlag(
+			"", "fish-completion-script",
+			"generate a fish script that sets up completion for this command; "+
+				"to use, run the following line or add it to your config.fish:\n"+
+				cmd.ProcessName+" --fish-completion-script | source",
+			ErrFishCompletionScriptRequested)
+		cmd.opts.AddSpecialFlag(
+			"", "powershell-completion-script",
+			"generate a PowerShell script that sets up completion for this command; "+
+				"to use, run the following line or add it to your $PROFILE:\n"+
+				cmd.ProcessName+" --powershell-completion-script | Out-String | Invoke-Expression",
+			ErrPowerShellCompletionScriptRequested)
+	}
+
+	if cmd.EnableShowConfig {
+		var opt = cmd.opts.AddSpecialFlag(
+			"", "show-config",
+			"display the effective configuration and the source of each value",
+			ErrShowConfigRequested)
+		if opt != nil {
+			opt.Deferred = true
+		}
+	}
+
+	if cmd.EnableSchema && cmd.parent == nil {
+		var opt = cmd.opts.AddSpecialFlag(
+			"", "cli-schema",
+			"generate a JSON document describing the command-line interface",
+			ErrSchemaRequested)
+		if opt != nil {
+			opt.Hidden = true
+		}
+	}
+
+	if cmd.EnableManPage && cmd.parent == nil {
+		var opt = cmd.opts.AddSpecialFlag(
+			"", "generate-man",
+			"generate a man page for the command in roff format",
+			ErrManPageRequested)
+		if opt != nil {
+			opt.Hidden = true
+		}
+	}
+}
+
+// execute applies the defaults, configuration file, environment variables and
+// command-line arguments to the command options, then either runs the command
+// handler or dispatches the remaining arguments to the selected sub-command.
+func (cmd *Command) execute() error {
+	if err := cmd.opts.ApplyDefaults(); err != nil {
+		return err
+	}
+
+	var flags, rest = cmd.ProcessArgs[1:], []string(nil)
+	if len(cmd.Subcommands) != 0 {
+		flags, rest = cmd.opts.SplitCommandArgs(flags)
+	}
+	// Configuration errors are reported only if no special flag like `--help`
+	// interrupts the processing, so that help remains available.
+	cmd.configErr = cmd.loadConfig(flags)
+	if err := cmd.opts.ApplyEnv(cmd.ProcessEnv); err != nil {
+		return err
+	}
+
+	if err := cmd.opts.ApplyArgs(flags); err != nil {
+		if !errors.Is(err, ErrShowConfigRequested) {
+			return err
+		}
+		cmd.showConfig = true
+	}
+	if len(cmd.Subcommands) == 0 {
+		return cmd.run()
+	}
+	if len(rest) == 0 {
+		if cmd.Handler != nil {
+			return cmd.run()
+		}
+		if err := cmd.chainConfigError(); err != nil {
+			return err
+		}
+		return &UsageError{Err: fmt.Errorf(
+			"missing command, expected one of: %v",
+			strings.Join(cmd.subcommandNames(), ", "))}
+	}
+
+	sub, err := cmd.subcommand(rest)
+	if err != nil {
+		return err
+	}
+	cmd.selected = sub
+	sub.argOffset = cmd.argOffset + len(cmd.ProcessArgs) - len(rest)
+	return sub.execute()
+}
+
+// validateChain validates the options of the command and of all its parent
+// commands, starting from the root command. It is called once the command to
+// run has been resolved and all its arguments applied, so that errors in the
+// arguments of a sub-command are reported first.
+func (cmd *Command) validateChain() error {
+	var chain []*Command
+	for c := cmd; c != nil; c = c.parent {
+		chain = append([]*Command{c}, chain...)
+	}
+	for _, c := range chain {
+		if err := c.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the option values against the constraints defined in their
+// tags and the rules of option groups, then calls the optional validation hook
+// of the command handler. Violations are reported as usage errors.
+func (cmd *Command) validate() error {
+	if err := cmd.opts.Validate(); err != nil {
+		return &UsageError{Err: err}
+	}
+	if vh, ok := cmd.Handler.(ValidateHandler); ok {
+		if err := vh.Validate(); err != nil {
+			return &UsageError{Err: err}
+		}
+	}
+	return nil
+}
+
+// run checks the configuration of the command chain and validates its
+// options, then prepares and runs the command handler, unless the effective
+// configuration was requested on the command or any of its parent commands.
+func (cmd *Command) run() error {
+	if err := cmd.chainConfigError(); err != nil {
+		return err
+	}
+	if cmd.showConfigRequested() {
+		return ErrShowConfigRequested
+	}
+	if err := cmd.validateChain(); err != nil {
+		return err
+	}
+	if ph, ok := cmd.Handler.(PrepareHandler); ok {
+		if err := ph.Prepare(); err != nil {
+			return err
+		}
+	}
+	return cmd.Handler.Run()
+}
+
+// chainConfigError returns the error raised while loading the configuration of
+// the command or any of its parent commands, starting from the root command.
+func (cmd *Command) chainConfigError() (err error) {
+	for c := cmd; c != nil; c = c.parent {
+		if c.configErr != nil {
+			err = c.configErr
+		}
+	}
+	return
+}
+
+// showConfigRequested returns true if the effective configuration was requested
+// on the command or any of its parent commands.
+func (cmd *Command) showConfigRequested() bool {
+	for c := cmd; c != nil; c = c.parent {
+		if c.showConfig {
+			return true
+		}
+	}
+	return false
+}
+
+// subcommand looks up the sub-command named by the first of `args` and seeds
+// it with the process context of the receiver and the remaining arguments.
+func (cmd *Command) subcommand(args []string) (*Command, error) {
+	var name = args[0]
+	var sub = cmd.Subcommands[name]
+	if sub == nil {
+		return nil, &UsageError{Err: &ErrInvalidCommand{
+			Command:     name,
+			Suggestions: option.Suggest(name, cmd.subcommandNames()),
+		}}
+	}
+
+	sub.parent = cmd
+	sub.selected = nil
+	sub.ProcessName = cmd.ProcessName + " " + name
+	sub.ProcessArgs = args
+	sub.ProcessEnv = cmd.ProcessEnv
+	sub.ConsoleWidth = cmd.ConsoleWidth
+	sub.DisableCompletion = cmd.DisableCompletion
+	sub.EnableShowConfig = cmd.EnableShowConfig
+	sub.AllowAbbreviations = cmd.AllowAbbreviations
+	sub.Color = cmd.Color
+	sub.Theme = cmd.Theme
+	sub.showConfig = false
+	sub.configErr = nil
+	sub.config = cmd.config.Section(name)
+
+	if err := sub.initialize(); err != nil {
+		return nil, err
+	}
+	sub.addSpecialFlags()
+	return sub, nil
+}
+
+// loadConfig loads the configuration file applicable to the command and
+// applies its values to the command options. The file is named by the option
+// tagged with `config-file` if any, or is the first existing file listed in
+// `ConfigSearchPath`. Otherwise, a sub-command uses the section named after it
+// in the configuration file of its parent command. A file named explicitly
+// through command-line arguments or environment must exist.
+func (cmd *Command) loadConfig(args []string) error {
+	var filename, explicit = cmd.opts.ConfigFilename(args, cmd.ProcessEnv)
+	if filename != "" && !explicit && !cmd.fileExists(filename) {
+		filename = ""
+	}
+	if filename == "" {
+		for _, f := range cmd.ConfigSearchPath {
+			if cmd.fileExists(f) {
+				filename = f
+				break
+			}
+		}
+	}
+
+	if filename != "" {
+		config, err := option.LoadConfig(cmd.expandHome(filename))
+		if err != nil {
+			return err
+		}
+		cmd.config = config
+	}
+	return cmd.opts.ApplyConfig(cmd.config)
+}
+
+// expandHome replaces a leading `~/` in `filename` with the home directory of
+// the current user, as defined by the `HOME` environment variable.
+func (cmd *Command) expandHome(filename string) string {
+	if home, ok := cmd.ProcessEnv["HOME"]; ok && strings.HasPrefix(filename, "~/") {
+		return filepath.Join(home, filename[2:])
+	}
+	return filename
+}
+
+func (cmd *Command) fileExists(filename string) bool {
+	_, err := os.Stat(cmd.expandHome(filename))
+	return err == nil
+}
+
+// subcommandNames returns the sorted list of names of all sub-commands.
+func (cmd *Command) subcommandNames() []string {
+	var names = make([]string, 0, len(cmd.Subcommands))
+	for name := range cmd.Subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// versionHandler returns the handler of the closest command in the chain of
+// parent commands that implements `VersionHandler`, or nil if none does.
+func (cmd *Command) versionHandler() VersionHandler {
+	for c := cmd; c != nil; c = c.parent {
+		if vh, ok := c.Handler.(VersionHandler); ok {
+			return vh
+		}
+	}
 	return nil
 }
@@ -1,10 +1,14 @@
 package cli_test
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/maargenton/go-testpredicate/pkg/bdd"
 	"github.com/maargenton/go-testpredicate/pkg/require"
 
 	"github.com/maargenton/go-cli/pkg/cli"
@@ -179,14 +183,145 @@ func TestCommandUsage(t *testing.T) {
 			usage := splitLines(cmd.Usage())
 
 			t.Run("then a command description is returned", func(t *testing.T) {
-				require.That(t, usage).Length().Eq(7)
+				require.That(t, usage).Length().Eq(11)
 				require.That(t, usage[0]).Contains("command-name")
 				require.That(t, usage[1]).Contains("command description")
-				require.That(t, usage[2]).Contains("<port>")
-				require.That(t, usage[3]).Contains("<aux-port>")
-				require.That(t, usage[4]).Contains("<ports>...")
-				require.That(t, usage[5]).Contains("--verbose")
-				require.That(t, usage[6]).Contains("TEST_ARG")
+				require.That(t, usage[3]).Eq("Arguments:")
+				require.That(t, usage[4]).Contains("<port>")
+				require.That(t, usage[5]).Contains("<aux-port>")
+				require.That(t, usage[6]).Contains("<ports>...")
+				require.That(t, usage[8]).Eq("Options:")
+				require.That(t, usage[9]).Contains("--verbose")
+				require.That(t, usage[10]).Contains("TEST_ARG")
+			})
+		})
+	})
+
+	t.Run("Given a command with a slice positional argument", func(t *testing.T) {
+		type myCmd2 struct {
+			myCmd
+			Src []string `opts:"arg:1, name:src, min-count:1" desc:"files to copy"`
+			Dst string   `opts:"arg:2, name:dst"              desc:"destination"`
+		}
+
+		var cmd = &cli.Command{
+			Handler:     &myCmd2{},
+			Description: "command description",
+		}
+
+		t.Run("when calling Usage()", func(t *testing.T) {
+			cmd.ProcessName = "command-name"
+			cmd.ConsoleWidth = 80
+			usage := splitLines(cmd.Usage())
+
+			t.Run("then the slice argument is shown in place", func(t *testing.T) {
+				require.That(t, usage[0]).Eq("Usage: command-name [options] <src>... <dst>")
+				require.That(t, usage[4]).Contains("files to copy, min count: 1")
+				require.That(t, usage[5]).Contains("<dst>")
+			})
+		})
+	})
+
+	t.Run("Given a command with sections, examples and footer", func(t *testing.T) {
+		type networkOptions struct {
+			Host string `opts:"--host" desc:"host to connect to"`
+			Port int    `opts:"--port" desc:"port to connect to"`
+		}
+		type myCmd2 struct {
+			myCmd
+			networkOptions `opts:"section:Network options"`
+			Output         string `opts:"-o, --output, section:Output options" desc:"output file"`
+		}
+
+		var cmd = &cli.Command{
+			Handler:     &myCmd2{},
+			Description: "command description",
+			Examples:    "command-name --host example.com\ncommand-name -o out.txt\n",
+			Footer:      "See the project page for more details.",
+		}
+
+		t.Run("when calling Usage()", func(t *testing.T) {
+			cmd.ProcessName = "command-name"
+			cmd.ProcessArgs = []string{"command-name"}
+			cmd.ConsoleWidth = 80
+			cmd.Run()
+			usage := splitLines(cmd.Usage())
+
+			t.Run("then options are listed by section", func(t *testing.T) {
+				require.That(t, usage[:14]).Eq([]string{
+					"Usage: command-name [options]",
+					"command description",
+					"",
+					"Options:",
+					"  -v, --verbose    ",
+					"  -a, --arg <value> : env: TEST_ARG",
+					"",
+					"Network options:",
+					"      --host <value> : host to connect to",
+					"      --port <value> : port to connect to",
+					"",
+					"Output options:",
+					"  -o, --output <value> : output file",
+					"",
+				})
+			})
+			t.Run("then special flags are listed last as global options", func(t *testing.T) {
+				require.That(t, usage[14]).Eq("Global options:")
+				require.That(t, usage[15]).StartsWith("  -V, --version ")
+				require.That(t, usage[16]).StartsWith("  -h, --help ")
+			})
+			t.Run("then examples and footer are displayed at the end", func(t *testing.T) {
+				require.That(t, usage[len(usage)-5:]).Eq([]string{
+					"Examples:",
+					"  command-name --host example.com",
+					"  command-name -o out.txt",
+					"",
+					"See the project page for more details.",
+				})
+			})
+		})
+	})
+
+	t.Run("Given a command with colors enabled", func(t *testing.T) {
+		type myCmd2 struct {
+			myCmd
+			Port string `opts:"arg:1, name:port" desc:"port to open"`
+		}
+
+		var cmd = &cli.Command{
+			Handler:     &myCmd2{},
+			Description: "command description",
+			Color:       true,
+		}
+
+		t.Run("when calling Usage()", func(t *testing.T) {
+			cmd.ProcessName = "command-name"
+			cmd.ConsoleWidth = 80
+			usage := splitLines(cmd.Usage())
+
+			t.Run("then headings, flags and values are styled", func(t *testing.T) {
+				require.That(t, usage).Eq([]string{
+					"\x1b[1mUsage:\x1b[0m command-name [options] <port>",
+					"command description",
+					"",
+					"\x1b[1mArguments:\x1b[0m",
+					"  \x1b[33m<port>\x1b[0m : port to open",
+					"",
+					"\x1b[1mOptions:\x1b[0m",
+					"  \x1b[36m-v, --verbose\x1b[0m    ",
+					"  \x1b[36m-a, --arg\x1b[0m \x1b[33m<value>\x1b[0m : env: TEST_ARG",
+				})
+			})
+		})
+
+		t.Run("when calling Usage() with a custom theme", func(t *testing.T) {
+			cmd.ProcessName = "command-name"
+			cmd.Theme = &cli.Theme{Heading: "4"}
+			usage := splitLines(cmd.Usage())
+
+			t.Run("then the styles of the theme are used", func(t *testing.T) {
+				require.That(t, usage[3]).Eq("\x1b[4mArguments:\x1b[0m")
+				require.That(t, usage[4]).Eq("  <port> : port to open")
 			})
 		})
 	})
@@ -261,3 +396,981 @@ func TestCommandVersion(t *testing.T) {
 		})
 	})
 }
+
+// ---------------------------------------------------------------------------
+// Sub-commands
+// ---------------------------------------------------------------------------
+
+type rootCmd struct {
+	Verbose bool   `opts:"-v, --verbose"`
+	Config  string `opts:"-c, --config"`
+	didRun  bool
+}
+
+func (c *rootCmd) Run() error {
+	c.didRun = true
+	return nil
+}
+
+func (c *rootCmd) Version() string {
+	return "v1.2.3"
+}
+
+type migrateCmd struct {
+	DryRun bool   `opts:"-n, --dry-run"`
+	Target string `opts:"arg:1, name:target" desc:"target schema version"`
+	didRun bool
+}
+
+func (c *migrateCmd) Run() error {
+	c.didRun = true
+	return nil
+}
+
+func newCommandTree() (*cli.Command, *rootCmd, *migrateCmd) {
+	var root = &rootCmd{}
+	var migrate = &migrateCmd{}
+	var cmd = &cli.Command{
+		Handler:     root,
+		Description: "command description",
+		Subcommands: map[string]*cli.Command{
+			"db": {
+				Description: "database operations",
+				Subcommands: map[string]*cli.Command{
+					"migrate": {
+						Handler:     migrate,
+						Description: "migrate the database schema",
+					},
+				},
+			},
+			"user": {
+				Handler:     &myCmd{},
+				Description: "user operations",
+			},
+		},
+	}
+	return cmd, root, migrate
+}
+
+func TestCommandRunSubcommands(t *testing.T) {
+	t.Run("Given a command with nested sub-commands", func(t *testing.T) {
+		t.Run("when calling run with a leaf sub-command", func(t *testing.T) {
+			var cmd, root, migrate = newCommandTree()
+			cmd.ProcessArgs = []string{"tool", "-v", "--config", "db", "db", "migrate", "--dry-run", "v2"}
+			err := cmd.Run()
+
+			t.Run("then the options of every level are set", func(t *testing.T) {
+				require.That(t, err).IsNil()
+				require.That(t, root.Verbose).IsTrue()
+				require.That(t, root.Config).Eq("db")
+				require.That(t, migrate.DryRun).IsTrue()
+				require.That(t, migrate.Target).Eq("v2")
+			})
+			t.Run("then only the leaf handler is run", func(t *testing.T) {
+				require.That(t, migrate.didRun).IsTrue()
+				require.That(t, root.didRun).IsFalse()
+			})
+		})
+
+		t.Run("when calling run without sub-command", func(t *testing.T) {
+			var cmd, root, _ = newCommandTree()
+			cmd.ProcessArgs = []string{"tool", "-v"}
+			err := cmd.Run()
+
+			t.Run("then the parent handler is run", func(t *testing.T) {
+				require.That(t, err).IsNil()
+				require.That(t, root.didRun).IsTrue()
+			})
+		})
+
+		t.Run("when calling run without sub-command and no parent handler", func(t *testing.T) {
+			var cmd, _, _ = newCommandTree()
+			cmd.ProcessArgs = []string{"tool", "db"}
+			err := cmd.Run()
+
+			t.Run("then an error lists the available sub-commands", func(t *testing.T) {
+				require.That(t, err).ToString().Contains("missing command")
+				require.That(t, err).ToString().Contains("migrate")
+			})
+		})
+
+		t.Run("when calling run with an unknown sub-command", func(t *testing.T) {
+			var cmd, _, _ = newCommandTree()
+			cmd.ProcessArgs = []string{"tool", "db", "drop"}
+			err := cmd.Run()
+
+			t.Run("then an error is returned", func(t *testing.T) {
+				require.That(t, err).ToString().Contains("invalid command 'drop'")
+			})
+		})
+
+		t.Run("when calling run with a parent option after the sub-command", func(t *testing.T) {
+			var cmd, _, migrate = newCommandTree()
+			cmd.ProcessArgs = []string{"tool", "db", "migrate", "--config", "x", "v2"}
+			err := cmd.Run()
+
+			t.Run("then the option is rejected", func(t *testing.T) {
+				require.That(t, err).ToString().Contains("invalid flag '--config'")
+				require.That(t, migrate.didRun).IsFalse()
+			})
+		})
+
+		t.Run("when requesting help for a sub-command", func(t *testing.T) {
+			var cmd, _, _ = newCommandTree()
+			cmd.ProcessName = "tool"
+			cmd.ProcessArgs = []string{"tool", "db", "migrate", "--help"}
+			cmd.ConsoleWidth = 80
+			err := cmd.Run()
+			usage := splitLines(cmd.Usage())
+
+			t.Run("then the usage of the sub-command is returned", func(t *testing.T) {
+				require.That(t, err).IsError(cli.ErrHelpRequested)
+				require.That(t, usage[0]).Eq("Usage: tool db migrate [options] <target>")
+				require.That(t, usage[1]).Eq("migrate the database schema")
+			})
+		})
+
+		t.Run("when requesting the version from a sub-command", func(t *testing.T) {
+			var cmd, _, _ = newCommandTree()
+			cmd.ProcessArgs = []string{"tool", "db", "--version"}
+			err := cmd.Run()
+
+			t.Run("then the version of the parent command is returned", func(t *testing.T) {
+				require.That(t, err).IsError(cli.ErrVersionRequested)
+				require.That(t, cmd.Version()).Eq("v1.2.3")
+			})
+		})
+	})
+
+	t.Run("Given a command with sub-commands and positional arguments", func(t *testing.T) {
+		var cmd = &cli.Command{
+			Handler: &migrateCmd{},
+			Subcommands: map[string]*cli.Command{
+				"user": {Handler: &myCmd{}},
+			},
+		}
+
+		t.Run("when calling run", func(t *testing.T) {
+			cmd.ProcessArgs = []string{"tool", "user"}
+			err := cmd.Run()
+
+			t.Run("then an error is returned", func(t *testing.T) {
+				require.That(t, err).ToString().Contains("cannot capture positional arguments")
+			})
+		})
+	})
+}
+
+func TestCommandUsageSubcommands(t *testing.T) {
+	t.Run("Given a command with sub-commands", func(t *testing.T) {
+		var cmd, _, _ = newCommandTree()
+
+		t.Run("when calling Usage()", func(t *testing.T) {
+			cmd.ProcessName = "tool"
+			cmd.ConsoleWidth = 80
+			usage := splitLines(cmd.Usage())
+
+			t.Run("then the sub-commands are listed with their description", func(t *testing.T) {
+				require.That(t, usage[0]).Eq("Usage: tool [options] <command> [<args>]")
+				require.That(t, usage).IsSupersetOf([]string{
+					"Commands:",
+					"  db   : database operations",
+					"  user : user operations",
+				})
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Schema
+// ---------------------------------------------------------------------------
+
+func TestCommandSchema(t *testing.T) {
+	bdd.Given(t, "a command with nested sub-commands", func(t *bdd.T) {
+		var cmd, _, _ = newCommandTree()
+		cmd.ProcessName = "tool"
+
+		t.When("calling Schema()", func(t *bdd.T) {
+			schema, err := cmd.Schema()
+
+			t.Then("the root command is described", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, schema.SchemaVersion).Eq(cli.SchemaVersion)
+				require.That(t, schema.Name).Eq("tool")
+				require.That(t, schema.Version).Eq("v1.2.3")
+				require.That(t, schema.Options).Field("Long").IsSupersetOf(
+					[]string{"verbose", "config", "help"})
+			})
+			t.Then("sub-commands are described recursively", func(t *bdd.T) {
+				require.That(t, schema.Subcommands).Field("Name").Eq(
+					[]string{"db", "user"})
+				var migrate = schema.Subcommands[0].Subcommands[0]
+				require.That(t, migrate.Name).Eq("migrate")
+				require.That(t, migrate.Positional).Field("ValueName").Eq(
+					[]string{"target"})
+			})
+			t.Then("sub-commands inherit the version of the parent", func(t *bdd.T) {
+				require.That(t, schema.Subcommands[0].Version).Eq("v1.2.3")
+				require.That(t, schema.Subcommands[0].Subcommands[0].Version).Eq("v1.2.3")
+			})
+		})
+	})
+
+	bdd.Given(t, "a command with schema enabled", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler:      &myCmd{},
+			EnableSchema: true,
+		}
+
+		t.When("calling Run() with --cli-schema", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--cli-schema"}
+			err := cmd.Run()
+
+			t.Then("the schema request error is returned", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrSchemaRequested)
+			})
+		})
+
+		t.When("calling Usage()", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name"}
+			cmd.Run()
+			usage := cmd.Usage()
+
+			t.Then("the schema flag is not listed", func(t *bdd.T) {
+				require.That(t, usage).Contains("--help")
+				require.That(t, strings.Contains(usage, "--cli-schema")).IsFalse()
+			})
+		})
+
+		t.When("calling Schema()", func(t *bdd.T) {
+			schema, err := cmd.Schema()
+
+			t.Then("the schema flag is not described", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				for _, opt := range schema.Options {
+					require.That(t, opt.Long != "cli-schema").IsTrue()
+				}
+			})
+		})
+	})
+}
+
+func TestCommandManPage(t *testing.T) {
+	bdd.Given(t, "a command with sub-commands", func(t *bdd.T) {
+		var cmd, _, _ = newCommandTree()
+		cmd.ProcessName = "tool"
+
+		t.When("calling ManPage()", func(t *bdd.T) {
+			page, err := cmd.ManPage()
+			var lines = splitLines(page)
+
+			t.Then("the page header and sections are generated", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, lines[0]).Eq(`.TH "TOOL" 1 "" "tool v1.2.3" "User Commands"`)
+				require.That(t, lines).IsSupersetOf([]string{
+					".SH NAME", `tool \- command description`,
+					".SH SYNOPSIS", ".B tool", "[options] <command> [<args>]",
+					".SH DESCRIPTION", ".SH OPTIONS", ".SH COMMANDS",
+				})
+			})
+			t.Then("options are listed with escaped dashes", func(t *bdd.T) {
+				require.That(t, lines).IsSupersetOf([]string{
+					`.B \-v, \-\-verbose`, `.B \-h, \-\-help`, "display usage information",
+				})
+			})
+			t.Then("sub-commands are listed with their options", func(t *bdd.T) {
+				require.That(t, lines).IsSupersetOf([]string{
+					`.B tool db migrate [options] <target>`,
+					"migrate the database schema",
+					`.B \-n, \-\-dry\-run`,
+					"target schema version",
+				})
+			})
+		})
+	})
+
+	bdd.Given(t, "a command with environment variables", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler:       &configCmd{},
+			Description:   "serve requests\n\n.details on a separate paragraph",
+			ProcessName:   "server",
+			EnableManPage: true,
+		}
+
+		t.When("calling ManPage()", func(t *bdd.T) {
+			page, err := cmd.ManPage()
+			var lines = splitLines(page)
+
+			t.Then("the environment section lists the variables", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, lines).IsSupersetOf([]string{
+					".SH ENVIRONMENT", ".B PORT", `Sets \-\-port`,
+				})
+			})
+			t.Then("the description is split into paragraphs", func(t *bdd.T) {
+				require.That(t, lines).IsSupersetOf([]string{
+					"serve requests", ".PP", `\&.details on a separate paragraph`,
+				})
+			})
+			t.Then("the man page flag is not listed", func(t *bdd.T) {
+				require.That(t, strings.Contains(page, `generate\-man`)).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with --generate-man", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"server", "--generate-man"}
+			err := cmd.Run()
+
+			t.Then("the man page request error is returned", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrManPageRequested)
+			})
+		})
+	})
+}
+
+type markdownCmd struct {
+	Port    int      `opts:"-p, --port, default:8080, env:PORT, min:1" desc:"port to listen on"`
+	Color   bool     `opts:"--color, negatable"                        desc:"colorize the output"`
+	Format  string   `opts:"-f, --format, name:fmt, choices:json|yaml" desc:"output format"`
+	Log     string   `opts:"--log, optional-value:info"                desc:"log level"`
+	Root    string   `opts:"arg:1, name:root"                          desc:"root directory"`
+	Include []string `opts:"args, name:patterns"                       desc:"patterns of files\nto include"`
+}
+
+func (c *markdownCmd) Run() error {
+	return nil
+}
+
+func TestCommandMarkdown(t *testing.T) {
+	bdd.Given(t, "a command with options and arguments", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler:     &markdownCmd{},
+			Description: "serve files from a directory",
+			ProcessName: "serve",
+		}
+
+		t.When("calling Markdown()", func(t *bdd.T) {
+			doc, err := cmd.Markdown()
+
+			t.Then("the documentation matches the golden output", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				golden, err := os.ReadFile("testdata/markdown-command.md")
+				require.That(t, err).IsNil()
+				require.That(t, doc).Eq(string(golden))
+			})
+		})
+	})
+
+	bdd.Given(t, "a command with sub-commands", func(t *bdd.T) {
+		var cmd, _, _ = newCommandTree()
+		cmd.ProcessName = "tool"
+
+		t.When("calling Markdown()", func(t *bdd.T) {
+			doc, err := cmd.Markdown()
+
+			t.Then("the documentation matches the golden output", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				golden, err := os.ReadFile("testdata/markdown-subcommands.md")
+				require.That(t, err).IsNil()
+				require.That(t, doc).Eq(string(golden))
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+
+type configCmd struct {
+	Config  string `opts:"-c, --config, config-file"`
+	Host    string `opts:"--host, default:localhost"`
+	Port    int    `opts:"-p, --port, default:80, env:PORT"`
+	Verbose bool   `opts:"-v, --verbose"`
+	didRun  bool
+}
+
+func (c *configCmd) Run() error {
+	c.didRun = true
+	return nil
+}
+
+func TestCommandRunConfig(t *testing.T) {
+	bdd.Given(t, "a command with a configuration file", func(t *bdd.T) {
+		var dir = t.TempDir()
+		var filename = filepath.Join(dir, "config.yaml")
+		var content = "host: example.com\nport: 8080\nverbose: true\n" +
+			"migrate:\n  dry-run: true\n"
+		require.That(t, os.WriteFile(filename, []byte(content), 0644)).IsNil()
+
+		var c = &configCmd{}
+		var cmd = &cli.Command{
+			Handler:     c,
+			Description: "command description",
+		}
+
+		t.When("calling Run() with the configuration file argument", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--config", filename}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("values from the file override defaults", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, c.Host).Eq("example.com")
+				require.That(t, c.Port).Eq(8080)
+				require.That(t, c.Verbose).IsTrue()
+			})
+		})
+
+		t.When("calling Run() with environment and arguments", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "-c", filename, "--host", "127.0.0.1"}
+			cmd.ProcessEnv = map[string]string{"PORT": "9090"}
+			err := cmd.Run()
+
+			t.Then("values from environment and arguments override the file", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, c.Host).Eq("127.0.0.1")
+				require.That(t, c.Port).Eq(9090)
+			})
+		})
+
+		t.When("calling Run() with a configuration search path", func(t *bdd.T) {
+			cmd.ConfigSearchPath = []string{filepath.Join(dir, "missing.yaml"), filename}
+			cmd.ProcessArgs = []string{"command-name"}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("the first existing file is loaded", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, c.Host).Eq("example.com")
+			})
+		})
+
+		t.When("calling Run() with a missing configuration file argument", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "-c", filepath.Join(dir, "missing.yaml")}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).IsError(os.ErrNotExist)
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with an invalid configuration file and --help", func(t *bdd.T) {
+			var invalid = filepath.Join(dir, "invalid.yaml")
+			require.That(t, os.WriteFile(invalid, []byte("host: [\n"), 0644)).IsNil()
+			cmd.ProcessArgs = []string{"command-name", "-c", invalid, "--help"}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("help is displayed without reporting the configuration error", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrHelpRequested)
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with an invalid configuration file", func(t *bdd.T) {
+			var invalid = filepath.Join(dir, "invalid.yaml")
+			require.That(t, os.WriteFile(invalid, []byte("host: [\n"), 0644)).IsNil()
+			cmd.ProcessArgs = []string{"command-name", "-c", invalid}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("the configuration error is returned as a usage error", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, errors.As(err, &usageErr)).IsTrue()
+				require.That(t, err).ToString().Contains("invalid.yaml")
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with an invalid value in the configuration file", func(t *bdd.T) {
+			var invalid = filepath.Join(dir, "invalid-value.yaml")
+			require.That(t, os.WriteFile(invalid, []byte("port: abc\n"), 0644)).IsNil()
+			cmd.ProcessArgs = []string{"command-name", "-c", invalid}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("a usage error citing the file and line is returned", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, errors.As(err, &usageErr)).IsTrue()
+				require.That(t, err).ToString().Contains("invalid-value.yaml:1:")
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+	})
+
+	bdd.Given(t, "a command with sub-commands and a configuration file", func(t *bdd.T) {
+		var dir = t.TempDir()
+		var filename = filepath.Join(dir, "config.toml")
+		var content = "verbose = true\n\n[db.migrate]\ndry-run = true\n"
+		require.That(t, os.WriteFile(filename, []byte(content), 0644)).IsNil()
+
+		var cmd, root, migrate = newCommandTree()
+		cmd.ConfigSearchPath = []string{filename}
+
+		t.When("calling Run() with a nested sub-command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "db", "migrate", "v2"}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("each command reads its own section", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, root.Verbose).IsTrue()
+				require.That(t, migrate.DryRun).IsTrue()
+				require.That(t, migrate.didRun).IsTrue()
+			})
+		})
+	})
+}
+
+func TestCommandShowConfig(t *testing.T) {
+	bdd.Given(t, "a command with show-config enabled", func(t *bdd.T) {
+		var dir = t.TempDir()
+		var filename = filepath.Join(dir, "config.yaml")
+		require.That(t, os.WriteFile(filename, []byte("host: example.com\n"), 0644)).IsNil()
+
+		var c = &configCmd{}
+		var cmd = &cli.Command{
+			ProcessName:      "command-name",
+			Handler:          c,
+			Description:      "command description",
+			EnableShowConfig: true,
+		}
+
+		t.When("calling Run() with --show-config", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--show-config", "-c", filename, "-v"}
+			cmd.ProcessEnv = map[string]string{"PORT": "9090"}
+			err := cmd.Run()
+
+			t.Then("the handler is not run", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrShowConfigRequested)
+				require.That(t, c.didRun).IsFalse()
+			})
+			t.Then("the configuration lists values and sources", func(t *bdd.T) {
+				var config = cmd.ShowConfig()
+				require.That(t, config).Contains("command-name:\n")
+				require.That(t, config).Contains("example.com (config " + filename + ":1)")
+				require.That(t, config).Contains("9090 (env PORT)")
+				require.That(t, config).Contains("--verbose : true (arg 4)")
+				require.That(t, strings.Contains(config, "--show-config")).IsFalse()
+			})
+		})
+	})
+
+	bdd.Given(t, "a command with sub-commands and show-config enabled", func(t *bdd.T) {
+		var cmd, root, migrate = newCommandTree()
+		cmd.ProcessName = "tool"
+		cmd.EnableShowConfig = true
+
+		t.When("calling Run() with --show-config on the root command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "--show-config", "-v", "db", "migrate", "v2"}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("the selected command is not run", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrShowConfigRequested)
+				require.That(t, root.didRun).IsFalse()
+				require.That(t, migrate.didRun).IsFalse()
+			})
+			t.Then("the configuration of every selected command is listed", func(t *bdd.T) {
+				var config = cmd.ShowConfig()
+				require.That(t, config).Contains("tool:\n")
+				require.That(t, config).Contains("true (arg 2)")
+				require.That(t, config).Contains("tool db migrate:\n")
+				require.That(t, config).Contains("v2 (arg 5)")
+			})
+		})
+
+		t.When("calling Run() with --show-config on a sub-command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "db", "migrate", "--show-config", "v2"}
+			cmd.ProcessEnv = map[string]string{}
+			err := cmd.Run()
+
+			t.Then("the selected command is not run", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrShowConfigRequested)
+				require.That(t, migrate.didRun).IsFalse()
+				require.That(t, migrate.Target).Eq("v2")
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Validation constraints
+// ---------------------------------------------------------------------------
+
+type validatedCmd struct {
+	Port   int    `opts:"-p, --port, required, min:1"     desc:"port to listen on"`
+	Format string `opts:"-f, --format, choices:json|text" desc:"output format"`
+	didRun bool
+}
+
+func (c *validatedCmd) Run() error {
+	c.didRun = true
+	return nil
+}
+
+func TestCommandRunValidation(t *testing.T) {
+	bdd.Given(t, "a command with validation constraints", func(t *bdd.T) {
+		var c = &validatedCmd{}
+		var cmd = &cli.Command{
+			ProcessName:  "command-name",
+			ConsoleWidth: 80,
+			Handler:      c,
+			Description:  "command description",
+		}
+
+		t.When("calling Run() with invalid values", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "-f", "xml"}
+			err := cmd.Run()
+
+			t.Then("all violations are reported and the handler is not run", func(t *bdd.T) {
+				require.That(t, err).IsNotNil()
+				require.That(t, err).ToString().Contains("missing required value for '--port'")
+				require.That(t, err).ToString().Contains("value 'xml' for '--format'")
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with valid values", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "-p", "80", "-f", "json"}
+			err := cmd.Run()
+
+			t.Then("the handler is run", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, c.didRun).IsTrue()
+			})
+		})
+
+		t.When("calling Usage()", func(t *bdd.T) {
+			usage := cmd.Usage()
+
+			t.Then("the constraints are listed", func(t *bdd.T) {
+				require.That(t, usage).Contains("port to listen on, required, min: 1")
+				require.That(t, usage).Contains("output format, choices: json|text")
+			})
+		})
+	})
+
+	bdd.Given(t, "a command with a required option and sub-commands", func(t *bdd.T) {
+		var c = &validatedCmd{}
+		var migrate = &migrateCmd{}
+		var cmd = &cli.Command{
+			ProcessName:  "tool",
+			ConsoleWidth: 80,
+			Handler:      c,
+			Subcommands: map[string]*cli.Command{
+				"migrate": {Handler: migrate},
+			},
+		}
+
+		t.When("requesting the help of a sub-command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "migrate", "--help"}
+			err := cmd.Run()
+
+			t.Then("the help is returned without validating the parent", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrHelpRequested)
+			})
+		})
+
+		t.When("calling Run() with invalid sub-command arguments", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "migrate"}
+			err := cmd.Run()
+
+			t.Then("the error of the sub-command is reported first", func(t *bdd.T) {
+				require.That(t, err).ToString().Eq("missing argument for '<target>'")
+				require.That(t, migrate.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with valid sub-command arguments", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "migrate", "v2"}
+			err := cmd.Run()
+
+			t.Then("the constraints of the parent are checked", func(t *bdd.T) {
+				require.That(t, err).ToString().Eq("missing required value for '--port'")
+				require.That(t, migrate.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with valid arguments", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "-p", "80", "migrate", "v2"}
+			err := cmd.Run()
+
+			t.Then("the sub-command is run", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, migrate.didRun).IsTrue()
+			})
+		})
+	})
+}
+
+type groupCmd struct {
+	File   string `opts:"--file, exclusive:input, one-required:input" desc:"read input from file"`
+	URL    string `opts:"--url, exclusive:input, one-required:input"  desc:"read input from URL"`
+	Cert   string `opts:"--cert, together:tls"                        desc:"TLS certificate"`
+	Key    string `opts:"--key, together:tls"                         desc:"TLS private key"`
+	didRun bool
+}
+
+func (c *groupCmd) Run() error {
+	c.didRun = true
+	return nil
+}
+
+func TestCommandRunGroups(t *testing.T) {
+	bdd.Given(t, "a command with option groups", func(t *bdd.T) {
+		var c = &groupCmd{}
+		var cmd = &cli.Command{
+			ProcessName:  "command-name",
+			ConsoleWidth: 80,
+			Handler:      c,
+			Description:  "command description",
+		}
+
+		t.When("calling Run() with mutually exclusive options", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--file", "a.txt", "--url", "http://x"}
+			err := cmd.Run()
+
+			t.Then("an error is returned and the handler is not run", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("mutually exclusive")
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Usage()", func(t *bdd.T) {
+			usage := splitLines(cmd.Usage())
+
+			t.Then("the groups are listed", func(t *bdd.T) {
+				require.That(t, usage).IsSupersetOf([]string{
+					"Option groups:",
+					"  --file, --url : mutually exclusive, at least one required",
+					"  --cert, --key : must be used together",
+				})
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Validate and Prepare hooks
+// ---------------------------------------------------------------------------
+
+type hookCmd struct {
+	Min       int  `opts:"--min"`
+	Max       int  `opts:"--max"`
+	Fail      bool `opts:"--fail"`
+	prepared  bool
+	didRun    bool
+	callOrder []string
+}
+
+func (c *hookCmd) Validate() error {
+	c.callOrder = append(c.callOrder, "validate")
+	if c.Min > c.Max {
+		return fmt.Errorf("--min must not be greater than --max")
+	}
+	return nil
+}
+
+func (c *hookCmd) Prepare() error {
+	c.callOrder = append(c.callOrder, "prepare")
+	c.prepared = true
+	if c.Fail {
+		return fmt.Errorf("prepare failed")
+	}
+	return nil
+}
+
+func (c *hookCmd) Run() error {
+	c.callOrder = append(c.callOrder, "run")
+	c.didRun = true
+	return nil
+}
+
+func TestCommandRunHooks(t *testing.T) {
+	bdd.Given(t, "a command with validate and prepare hooks", func(t *bdd.T) {
+		var c = &hookCmd{}
+		var cmd = &cli.Command{
+			ProcessName: "command-name",
+			Handler:     c,
+			Description: "command description",
+		}
+
+		t.When("calling Run() with consistent options", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--min", "1", "--max", "2"}
+			err := cmd.Run()
+
+			t.Then("hooks are called in order before the handler", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, c.callOrder).Eq([]string{"validate", "prepare", "run"})
+			})
+		})
+
+		t.When("calling Run() with inconsistent options", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--min", "3", "--max", "2"}
+			err := cmd.Run()
+
+			t.Then("a usage error is returned", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, errors.As(err, &usageErr)).IsTrue()
+				require.That(t, err).ToString().Eq("--min must not be greater than --max")
+				require.That(t, c.prepared).IsFalse()
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+
+		t.When("calling Run() with a failing prepare hook", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--fail"}
+			err := cmd.Run()
+
+			t.Then("a runtime error is returned", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, err).IsNotNil()
+				require.That(t, errors.As(err, &usageErr)).IsFalse()
+				require.That(t, c.didRun).IsFalse()
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Exit codes and usage errors
+// ---------------------------------------------------------------------------
+
+type exitCmd struct {
+	Code int `opts:"--code, env:EXIT_CODE"`
+}
+
+func (c *exitCmd) Run() error {
+	return cli.Exit(c.Code, fmt.Errorf("exit with code %v", c.Code))
+}
+
+func TestCommandRunExitCodes(t *testing.T) {
+	bdd.Given(t, "a command returning a custom exit code", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			ProcessName: "command-name",
+			Handler:     &exitCmd{},
+			Description: "command description",
+		}
+
+		t.When("calling Run()", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--code", "3"}
+			err := cmd.Run()
+
+			t.Then("the error carries the exit code", func(t *bdd.T) {
+				var exitErr cli.ExitCoder
+				require.That(t, errors.As(err, &exitErr)).IsTrue()
+				require.That(t, exitErr.ExitCode()).Eq(3)
+				require.That(t, err).ToString().Eq("exit with code 3")
+			})
+		})
+
+		t.When("calling Run() with an invalid flag", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--cod", "3"}
+			err := cmd.Run()
+
+			t.Then("a usage error with exit code 2 is returned", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, errors.As(err, &usageErr)).IsTrue()
+				require.That(t, usageErr.ExitCode()).Eq(2)
+				require.That(t, cmd.UsageHint()).Eq("Run 'command-name --help' for usage.")
+			})
+		})
+
+		t.When("calling Run() with an invalid value in the environment", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name"}
+			cmd.ProcessEnv = map[string]string{"EXIT_CODE": "abc"}
+			err := cmd.Run()
+
+			t.Then("a usage error with exit code 2 is returned", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, errors.As(err, &usageErr)).IsTrue()
+				require.That(t, usageErr.ExitCode()).Eq(2)
+			})
+		})
+	})
+
+	bdd.Given(t, "a command with sub-commands", func(t *bdd.T) {
+		var cmd, _, _ = newCommandTree()
+		cmd.ProcessName = "tool"
+
+		t.When("calling Run() with an invalid command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "db", "drop"}
+			err := cmd.Run()
+
+			t.Then("a usage error is returned with a hint for the parent command", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, errors.As(err, &usageErr)).IsTrue()
+				require.That(t, err).ToString().Eq("invalid command 'drop'")
+				require.That(t, cmd.UsageHint()).Eq("Run 'tool db --help' for usage.")
+			})
+		})
+
+		t.When("calling Run() with a misspelled command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "db", "migrat", "v2"}
+			err := cmd.Run()
+
+			t.Then("the error suggests the closest command", func(t *bdd.T) {
+				var cmdErr *cli.ErrInvalidCommand
+				require.That(t, errors.As(err, &cmdErr)).IsTrue()
+				require.That(t, cmdErr.Suggestions).Eq([]string{"migrate"})
+				require.That(t, err).ToString().Eq("invalid command 'migrat', did you mean 'migrate'?")
+			})
+		})
+
+		t.When("calling Run() with a missing argument", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "db", "migrate"}
+			err := cmd.Run()
+
+			t.Then("a usage error is returned", func(t *bdd.T) {
+				var usageErr *cli.UsageError
+				require.That(t, errors.As(err, &usageErr)).IsTrue()
+				require.That(t, cmd.UsageHint()).Eq("Run 'tool db migrate --help' for usage.")
+			})
+		})
+	})
+}
+
+func TestCommandRunAbbreviations(t *testing.T) {
+	bdd.Given(t, "a command with sub-commands and abbreviations allowed", func(t *bdd.T) {
+		var cmd, root, migrate = newCommandTree()
+		cmd.ProcessName = "tool"
+		cmd.AllowAbbreviations = true
+
+		t.When("calling Run() with abbreviated flags", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "--verb", "db", "migrate", "--dry", "v2"}
+			err := cmd.Run()
+
+			t.Then("flags are resolved at every level", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, root.Verbose).IsTrue()
+				require.That(t, migrate.DryRun).IsTrue()
+			})
+		})
+	})
+}
+
+func TestColorEnabled(t *testing.T) {
+	var tcs = []struct {
+		env      map[string]string
+		terminal bool
+		enabled  bool
+	}{
+		{map[string]string{}, true, true},
+		{map[string]string{}, false, false},
+		{map[string]string{"NO_COLOR": "1"}, true, false},
+		{map[string]string{"NO_COLOR": ""}, true, true},
+		{map[string]string{"CLICOLOR_FORCE": "1"}, false, true},
+		{map[string]string{"CLICOLOR_FORCE": "0"}, false, false},
+		{map[string]string{"CLICOLOR_FORCE": "1", "NO_COLOR": "1"}, false, false},
+	}
+
+	for _, tc := range tcs {
+		var name = fmt.Sprintf("%v, terminal: %v", tc.env, tc.terminal)
+		t.Run(name, func(t *testing.T) {
+			require.That(t, cli.ColorEnabled(tc.env, tc.terminal)).Eq(tc.enabled)
+		})
+	}
+}
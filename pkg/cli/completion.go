@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/maargenton/go-fileutils"
 	"github.com/maargenton/go-fileutils/pkg/dir"
 
+	"github.com/maargenton/go-cli/pkg/enumer/enum"
 	"github.com/maargenton/go-cli/pkg/option"
 )
 
@@ -28,11 +30,87 @@ func BashCompletionScript(command string) string {
 	return fmt.Sprintf(bashCompletionTemplate, command)
 }
 
+// ZshCompletionScript returns a string containing the zsh script necessary to
+// setup native zsh completion for the command, including descriptions of the
+// suggested flags. It requires the zsh completion system to be initialized
+// with `compinit`.
+func ZshCompletionScript(command string) string {
+	var zshCompletionTemplate string = "" +
+		"_%[1]v_completion() {\n" +
+		"    local -a suggestions ;\n" +
+		"    local IFS=$'\\n' ;\n" +
+		"    suggestions=($(COMP_SHELL=zsh COMP_INDEX=$((CURRENT-1)) COMP_WORD=${words[CURRENT]} ${words[@]})) ;\n" +
+		"    _describe 'values' suggestions ;\n" +
+		"} ;\n" +
+		"compdef _%[1]v_completion %[1]v ;\n"
+
+	return fmt.Sprintf(zshCompletionTemplate, command)
+}
+
+// FishCompletionScript returns a string containing the fish script necessary
+// to setup completion for the command, including descriptions of the suggested
+// flags.
+func FishCompletionScript(command string) string {
+	var fishCompletionTemplate string = "" +
+		"function __%[1]v_completion\n" +
+		"    set -l word (commandline -ct)\n" +
+		"    set -l tokens (commandline -opc) \"$word\"\n" +
+		"    env COMP_SHELL=fish COMP_INDEX=(math (count $tokens) - 1) \"COMP_WORD=$word\" $tokens\n" +
+		"end\n" +
+		"complete -c %[1]v -f -a '(__%[1]v_completion)'\n"
+
+	return fmt.Sprintf(fishCompletionTemplate, command)
+}
+
+// PowerShellCompletionScript returns a string containing the PowerShell script
+// necessary to setup completion for the command, with descriptions of the
+// suggested flags shown as tooltips.
+func PowerShellCompletionScript(command string) string {
+	var powerShellCompletionTemplate string = "" +
+		"Register-ArgumentCompleter -Native -CommandName '%[1]v' -ScriptBlock {\n" +
+		"    param($wordToComplete, $commandAst, $cursorPosition)\n" +
+		"    $words = @($commandAst.CommandElements |\n" +
+		"        Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |\n" +
+		"        ForEach-Object { $_.Extent.Text })\n" +
+		"    $index = $words.Count\n" +
+		"    if ($wordToComplete -ne '') { $index = $words.Count - 1 }\n" +
+		"    $env:COMP_SHELL = 'pwsh'\n" +
+		"    $env:COMP_INDEX = $index\n" +
+		"    $env:COMP_WORD = $wordToComplete\n" +
+		"    $suggestions = & $words[0] @($words | Select-Object -Skip 1)\n" +
+		"    Remove-Item Env:COMP_SHELL, Env:COMP_INDEX, Env:COMP_WORD\n" +
+		"    $suggestions | ForEach-Object {\n" +
+		"        $value, $desc = $_ -split \"`t\", 2\n" +
+		"        $type = 'ParameterValue'\n" +
+		"        if ($value.StartsWith('-')) { $type = 'ParameterName' }\n" +
+		"        if (-not $desc) { $desc = $value }\n" +
+		"        [System.Management.Automation.CompletionResult]::new($value, $value, $type, $desc)\n" +
+		"    }\n" +
+		"}\n"
+
+	return fmt.Sprintf(powerShellCompletionTemplate, command)
+}
+
 // DefaultCompletion implements a default completion for a given option field,
 // and can be used a fallback by command completion handlers. It handles
-// specific cases based on the option field type, and simulates default shell
-// behavior (filename completion) for string types.
-func DefaultCompletion(opt *option.T, w string) []string {
+// specific cases based on the option field type, suggesting the canonical name
+// of all values for enum types and true / false for bools, and simulates
+// default shell behavior (filename completion) for other types.
+func DefaultCompletion(opt *option.T, w string) (r []string) {
+	if values, ok := enumCompletion(opt, w); ok {
+		for _, v := range values {
+			r = append(r, v.Option)
+		}
+		return r
+	}
+	if opt != nil && opt.Type == option.Bool {
+		for _, v := range []string{"true", "false"} {
+			if strings.HasPrefix(v, w) {
+				r = append(r, v)
+			}
+		}
+		return r
+	}
 	return DefaultFilenameCompletion(opt, w)
 }
 
@@ -69,6 +147,35 @@ func MatchingFilenameCompletion(opt *option.T, pattern string, w string) (r []st
 	return DefaultFilenameCompletion(opt, w)
 }
 
+// EnumCompletion returns the canonical names of the values of an option whose
+// value type was generated by enumer and implements `enum.Type`, along with
+// their description. It returns nil if the value type is not an enum type.
+func EnumCompletion(opt *option.T, w string) []option.Description {
+	var r, _ = enumCompletion(opt, w)
+	return r
+}
+
+// enumCompletion returns the enum values matching `w` and true if the value
+// type of the option implements `enum.Type`, or false otherwise.
+func enumCompletion(opt *option.T, w string) (r []option.Description, ok bool) {
+	if opt == nil || opt.ValueType == nil {
+		return nil, false
+	}
+	e, ok := reflect.New(opt.ValueType).Interface().(enum.Type)
+	if !ok {
+		return nil, false
+	}
+	for _, v := range e.EnumValues() {
+		if strings.HasPrefix(v.Name, w) {
+			r = append(r, option.Description{
+				Option:      v.Name,
+				Description: v.Description,
+			})
+		}
+	}
+	return r, true
+}
+
 // ---
 
 func (cmd *Command) handleCompletionRequest() bool {
@@ -86,33 +193,122 @@ func (cmd *Command) handleCompletionRequest() bool {
 		args = args[1:]
 	}
 
+	var shell = cmd.ProcessEnv["COMP_SHELL"]
+	for _, s := range cmd.getSuggestions(args, w) {
+		cmd.Suggestions = append(cmd.Suggestions, formatSuggestion(shell, s))
+	}
+	return true
+}
+
+// getSuggestions returns the completion suggestions for the partial argument
+// `w` following `args`, descending into the selected sub-command if any.
+// Suggestions are returned with their description, if any.
+func (cmd *Command) getSuggestions(args []string, w string) (suggestions []option.Description) {
+	if len(cmd.Subcommands) != 0 {
+		var flags, rest = cmd.opts.SplitCommandArgs(args)
+		if len(rest) != 0 {
+			sub, err := cmd.subcommand(rest)
+			if err != nil {
+				return nil
+			}
+			return sub.getSuggestions(rest[1:], w)
+		}
+		args = flags
+	}
+
 	var comp = cmd.opts.GetCompletion(args, w)
-	if comp.OptRef != nil {
+	var options []option.Description
+	for i, o := range comp.Options {
+		options = append(options, option.Description{
+			Option:      o,
+			Description: comp.OptionDescriptions[i],
+		})
+	}
+	if comp.OptRef != nil && comp.OptPrefix != "" {
+		for _, v := range cmd.complete(comp.OptRef, w[len(comp.OptPrefix):]) {
+			v.Option = comp.OptPrefix + v.Option
+			comp.OptValues = append(comp.OptValues, v)
+		}
+	} else if comp.OptRef != nil {
 		comp.OptValues = cmd.complete(comp.OptRef, w)
 	}
 	if comp.ArgRef != nil {
 		comp.ArgValues = cmd.complete(comp.ArgRef, w)
 	}
-
-	for _, o := range comp.Options {
-		if strings.HasPrefix(o, w) {
-			cmd.Suggestions = append(cmd.Suggestions, o)
+	if len(cmd.Subcommands) != 0 && comp.OptRef == nil && !cmd.hasSpecialFlag(args) {
+		for _, name := range cmd.subcommandNames() {
+			if strings.HasPrefix(name, w) {
+				suggestions = append(suggestions, option.Description{
+					Option:      name,
+					Description: cmd.Subcommands[name].Description,
+				})
+			}
 		}
 	}
-	for _, o := range comp.OptValues {
-		if strings.HasPrefix(o, w) {
-			cmd.Suggestions = append(cmd.Suggestions, o)
+
+	for _, values := range [][]option.Description{
+		options, comp.OptValues, comp.ArgValues,
+	} {
+		for _, v := range values {
+			if strings.HasPrefix(v.Option, w) {
+				suggestions = append(suggestions, v)
+			}
 		}
 	}
-	for _, o := range comp.ArgValues {
-		if strings.HasPrefix(o, w) {
-			cmd.Suggestions = append(cmd.Suggestions, o)
+
+	dumpCompletionRequest(comp)
+
+	return suggestions
+}
+
+// formatSuggestion formats a completion suggestion for the shell requesting
+// completion, as specified by the `COMP_SHELL` environment variable. Shells
+// that do not support descriptions receive only the suggested value.
+func formatSuggestion(shell string, s option.Description) string {
+	var desc = strings.Join(strings.Fields(s.Description), " ")
+	switch shell {
+	case "zsh":
+		var value = strings.ReplaceAll(s.Option, ":", "\\:")
+		if desc != "" {
+			return value + ":" + desc
+		}
+		return value
+	case "fish", "pwsh":
+		if desc != "" {
+			return s.Option + "\t" + desc
 		}
 	}
+	return s.Option
+}
 
-	dumpCompletionRequest(comp)
+// hasSpecialFlag returns true if any of `args` is a special flag, which
+// precludes any further argument. Deferred special flags like `--show-config`
+// do not interrupt parsing and are ignored.
+func (cmd *Command) hasSpecialFlag(args []string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			if opt := cmd.opts.GetOption(arg[2:]); isExclusiveSpecial(opt) {
+				return true
+			}
+		} else if strings.HasPrefix(arg, "-") {
+			for _, c := range arg[1:] {
+				var opt = cmd.opts.GetOption(string(c))
+				if isExclusiveSpecial(opt) {
+					return true
+				}
+				if opt == nil || (opt.Type != option.Bool && opt.Type != option.Count) {
+					break
+				}
+			}
+		}
+	}
+	return false
+}
 
-	return true
+// isExclusiveSpecial returns true if `opt` is a special flag that interrupts
+// parsing, like `--help` or `--version`.
+func isExclusiveSpecial(opt *option.T) bool {
+	return opt != nil && opt.Type == option.Special && !opt.Deferred
 }
 
 func (cmd *Command) getCompletionRequest() (index int, word string) {
@@ -132,11 +328,23 @@ func (cmd *Command) getCompletionRequest() (index int, word string) {
 	return
 }
 
-func (cmd *Command) complete(opt *option.T, word string) []string {
+func (cmd *Command) complete(opt *option.T, word string) []option.Description {
+	if handler, ok := cmd.Handler.(DescriptionCompletionHandler); ok {
+		return handler.CompleteWithDescription(opt, word)
+	}
+	var values []string
 	if handler, ok := cmd.Handler.(CompletionHandler); ok {
-		return handler.Complete(opt, word)
+		values = handler.Complete(opt, word)
+	} else if r, ok := enumCompletion(opt, word); ok {
+		return r
+	} else {
+		values = DefaultCompletion(opt, word)
+	}
+	var r = make([]option.Description, 0, len(values))
+	for _, v := range values {
+		r = append(r, option.Description{Option: v})
 	}
-	return DefaultCompletion(opt, word)
+	return r
 }
 
 func dumpCompletionRequest(comp option.Completion) {
@@ -147,6 +355,7 @@ func dumpCompletionRequest(comp option.Completion) {
 			"env": obj{
 				"COMP_INDEX": os.Getenv("COMP_INDEX"),
 				"COMP_WORD":  os.Getenv("COMP_WORD"),
+				"COMP_SHELL": os.Getenv("COMP_SHELL"),
 			},
 			"comp": comp,
 		}
@@ -1,6 +1,8 @@
 package cli_test
 
 import (
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/maargenton/go-fileutils"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/maargenton/go-cli/pkg/cli"
 	"github.com/maargenton/go-cli/pkg/option"
+	"github.com/maargenton/go-cli/pkg/strcase"
 )
 
 func TestBashCompletionScript(t *testing.T) {
@@ -19,6 +22,33 @@ func TestBashCompletionScript(t *testing.T) {
 	require.That(t, script).Contains("complete -F _command-name_completion command-name")
 }
 
+func TestZshCompletionScript(t *testing.T) {
+	var name = "command-name"
+	var script = cli.ZshCompletionScript(name)
+
+	require.That(t, script).Contains("_command-name_completion()")
+	require.That(t, script).Contains("COMP_SHELL=zsh")
+	require.That(t, script).Contains("_describe")
+	require.That(t, script).Contains("compdef _command-name_completion command-name")
+}
+
+func TestFishCompletionScript(t *testing.T) {
+	var name = "command-name"
+	var script = cli.FishCompletionScript(name)
+
+	require.That(t, script).Contains("function __command-name_completion")
+	require.That(t, script).Contains("COMP_SHELL=fish")
+	require.That(t, script).Contains("complete -c command-name -f -a '(__command-name_completion)'")
+}
+
+func TestPowerShellCompletionScript(t *testing.T) {
+	var script = cli.PowerShellCompletionScript("command-name")
+	var golden, err = os.ReadFile("testdata/powershell-completion.ps1")
+
+	require.That(t, err).IsNil()
+	require.That(t, script).Eq(string(golden))
+}
+
 func TestDefaultCompletion(t *testing.T) {
 	bdd.Given(t, "the current directory structure", func(t *bdd.T) {
 		t.When("calling DefaultCompletion() with an empty string", func(t *bdd.T) {
@@ -47,6 +77,101 @@ func TestDefaultCompletion(t *testing.T) {
 	})
 }
 
+func TestDefaultCompletionTypes(t *testing.T) {
+	type typedCmd struct {
+		Format  strcase.Format `opts:"-f, --format"`
+		Verbose *bool          `opts:"arg:1, name:verbose"`
+	}
+	var opts, err = option.NewOptionSet(&typedCmd{})
+	require.That(t, err).IsNil()
+
+	bdd.Given(t, "an option of a type generated by enumer", func(t *bdd.T) {
+		var opt = opts.GetOption("format")
+
+		t.When("calling DefaultCompletion() with an empty string", func(t *bdd.T) {
+			suggestions := cli.DefaultCompletion(opt, "")
+
+			t.Then("suggestions include the canonical name of all values", func(t *bdd.T) {
+				require.That(t, suggestions).Length().Eq(len(strcase.FormatValues))
+				require.That(t, suggestions).IsSupersetOf(
+					[]string{"camel-case", "snake-case", "filtered-hyphen-case"})
+			})
+		})
+		t.When("calling DefaultCompletion() with a non-matching value", func(t *bdd.T) {
+			suggestions := cli.DefaultCompletion(opt, "comp")
+
+			t.Then("no filename is suggested", func(t *bdd.T) {
+				require.That(t, suggestions).IsEmpty()
+			})
+		})
+	})
+
+	bdd.Given(t, "an option of bool type", func(t *bdd.T) {
+		var opt = opts.Positional[0]
+
+		t.When("calling DefaultCompletion() with an empty string", func(t *bdd.T) {
+			suggestions := cli.DefaultCompletion(opt, "")
+
+			t.Then("suggestions include true and false", func(t *bdd.T) {
+				require.That(t, suggestions).Eq([]string{"true", "false"})
+			})
+		})
+		t.When("calling DefaultCompletion() with a partial value", func(t *bdd.T) {
+			suggestions := cli.DefaultCompletion(opt, "f")
+
+			t.Then("suggestions include only the matching value", func(t *bdd.T) {
+				require.That(t, suggestions).Eq([]string{"false"})
+			})
+		})
+	})
+}
+
+type boolCmd struct {
+	Force   bool  `opts:"--force"`
+	Enabled *bool `opts:"arg:1, name:enabled"`
+}
+
+func (c *boolCmd) Run() error {
+	return nil
+}
+
+func TestCommandRunCompletionBool(t *testing.T) {
+	bdd.Given(t, "a command with bool options", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler: &boolCmd{},
+		}
+
+		t.When("calling Run() with a partial bool argument", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "f"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "f",
+				"COMP_INDEX": "1",
+			}
+			cmd.Suggestions = nil
+			err := cmd.Run()
+
+			t.Then("the matching bool value is suggested", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrCompletionRequested)
+				require.That(t, cmd.Suggestions).Eq([]string{"false"})
+			})
+		})
+
+		t.When("calling Run() with a partial value after a bool flag and '='", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--force=t"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "--force=t",
+				"COMP_INDEX": "1",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("the matching bool value is suggested with the flag prefix", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).Eq([]string{"--force=true"})
+			})
+		})
+	})
+}
+
 func TestMatchingFilenameCompletion(t *testing.T) {
 	bdd.Given(t, "a call to MatchingFilenameCompletion()", func(t *bdd.T) {
 		t.When("passing a pattern and an empty string", func(t *bdd.T) {
@@ -255,3 +380,349 @@ func TestCommandRunCompletionDebuf(t *testing.T) {
 		})
 	})
 }
+
+func TestCommandRunCompletionSubcommands(t *testing.T) {
+	bdd.Given(t, "a command with nested sub-commands", func(t *bdd.T) {
+		var cmd, _, _ = newCommandTree()
+
+		t.When("calling Run() with completion request for the sub-command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "-v", ""}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "",
+				"COMP_INDEX": "2",
+			}
+			cmd.Suggestions = nil
+			err := cmd.Run()
+
+			t.Then("the completion request error is returned", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrCompletionRequested)
+			})
+			t.Then("the suggestions include the sub-commands and the parent flags", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).IsSupersetOf(
+					[]string{"db", "user", "--config"})
+			})
+		})
+
+		t.When("calling Run() with completion request for a partial sub-command", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "--config", "foo", "db", "m"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "m",
+				"COMP_INDEX": "4",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("the suggestions contain the matching nested sub-command", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).Eq([]string{"migrate"})
+			})
+		})
+
+		t.When("calling Run() with completion request for a leaf flag", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "db", "migrate", "--d"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "--d",
+				"COMP_INDEX": "3",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("the suggestions contain the flag of the leaf command", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).Eq([]string{"--dry-run"})
+			})
+		})
+
+		t.When("calling Run() with completion request after a special flag", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"tool", "--help", ""}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "",
+				"COMP_INDEX": "2",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("no sub-command is suggested", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).IsEmpty()
+			})
+		})
+
+		t.When("calling Run() with completion request after --show-config", func(t *bdd.T) {
+			cmd.EnableShowConfig = true
+			cmd.ProcessArgs = []string{"tool", "--show-config", ""}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "",
+				"COMP_INDEX": "2",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("the sub-commands are still suggested", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).IsSupersetOf([]string{"db", "user"})
+			})
+		})
+	})
+}
+
+type descCmd struct {
+	Verbose bool   `opts:"-v, --verbose" desc:"display more details"`
+	Output  string `opts:"-o, --output"  desc:"output file, as\nhost:path"`
+	Format  string `opts:"-f, --format"`
+}
+
+func (c *descCmd) Run() error {
+	return nil
+}
+
+func TestCommandRunCompletionZsh(t *testing.T) {
+	bdd.Given(t, "a command with flag descriptions", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler:     &descCmd{},
+			Description: "command description",
+		}
+
+		t.When("calling Run() with a zsh completion request", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "--",
+				"COMP_INDEX": "1",
+				"COMP_SHELL": "zsh",
+			}
+			cmd.Suggestions = nil
+			err := cmd.Run()
+
+			t.Then("the completion request error is returned", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrCompletionRequested)
+			})
+			t.Then("the suggestions include the flag descriptions", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).IsSupersetOf([]string{
+					"--verbose:display more details",
+					"--output:output file, as host:path",
+					"--format",
+				})
+			})
+		})
+
+		t.When("calling Run() with a zsh completion request for a value", func(t *bdd.T) {
+			cmd.Handler = &compCmd2{}
+			cmd.ProcessArgs = []string{"command-name", "-v", ""}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "",
+				"COMP_INDEX": "2",
+				"COMP_SHELL": "zsh",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("the values are suggested without description", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).IsSupersetOf(
+					[]string{"ddd", "eee", "fff"})
+			})
+		})
+	})
+}
+
+func TestCommandRunCompletionFish(t *testing.T) {
+	bdd.Given(t, "a command with flag descriptions", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler:     &descCmd{},
+			Description: "command description",
+		}
+
+		t.When("calling Run() with a fish completion request", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "--",
+				"COMP_INDEX": "1",
+				"COMP_SHELL": "fish",
+			}
+			cmd.Suggestions = nil
+			err := cmd.Run()
+
+			t.Then("the completion request error is returned", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrCompletionRequested)
+			})
+			t.Then("the suggestions include tab-separated descriptions", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).IsSupersetOf([]string{
+					"--verbose\tdisplay more details",
+					"--output\toutput file, as host:path",
+					"--format",
+				})
+			})
+		})
+	})
+}
+
+func TestCommandRunCompletionPowerShell(t *testing.T) {
+	bdd.Given(t, "a command with flag descriptions", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler:     &descCmd{},
+			Description: "command description",
+		}
+
+		t.When("calling Run() with a PowerShell completion request", func(t *bdd.T) {
+			cmd.ProcessName = "command-name"
+			cmd.ProcessArgs = []string{"command-name", "-"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "-",
+				"COMP_INDEX": "1",
+				"COMP_SHELL": "pwsh",
+			}
+			cmd.Suggestions = nil
+			err := cmd.Run()
+
+			t.Then("the completion request error is returned", func(t *bdd.T) {
+				require.That(t, err).IsError(cli.ErrCompletionRequested)
+			})
+			t.Then("the suggestions match the golden output", func(t *bdd.T) {
+				golden, err := os.ReadFile("testdata/powershell-suggestions.txt")
+				require.That(t, err).IsNil()
+				require.That(t, strings.Join(cmd.Suggestions, "\n")+"\n").Eq(string(golden))
+			})
+		})
+	})
+}
+
+type descCmd2 struct {
+	descCmd
+}
+
+func (c *descCmd2) CompleteWithDescription(opt *option.T, partial string) []option.Description {
+	if opt.Long == "output" {
+		return []option.Description{
+			{Option: "stdout", Description: "standard output"},
+			{Option: "stderr", Description: "standard error"},
+		}
+	}
+	return nil
+}
+
+func TestCommandRunCompletionDescriptionHandler(t *testing.T) {
+	bdd.Given(t, "a command implementing DescriptionCompletionHandler", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler:     &descCmd2{},
+			Description: "command description",
+		}
+
+		t.When("calling Run() with a zsh completion request for a flag value", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--output", "std"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "std",
+				"COMP_INDEX": "2",
+				"COMP_SHELL": "zsh",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("the values are suggested with their description", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).Eq([]string{
+					"stdout:standard output",
+					"stderr:standard error",
+				})
+			})
+		})
+
+		t.When("calling Run() with a bash completion request for a flag value", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--output", "stdo"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "stdo",
+				"COMP_INDEX": "2",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("only the matching values are suggested, without description", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).Eq([]string{"stdout"})
+			})
+		})
+	})
+}
+
+func TestEnumCompletion(t *testing.T) {
+	type enumCmd struct {
+		Format strcase.Format `opts:"-f, --format"`
+		Name   string         `opts:"-n, --name"`
+	}
+	var opts, err = option.NewOptionSet(&enumCmd{})
+	require.That(t, err).IsNil()
+
+	bdd.Given(t, "an option of a type generated by enumer", func(t *bdd.T) {
+		var opt = opts.GetOption("format")
+
+		t.When("calling EnumCompletion() with a partial value", func(t *bdd.T) {
+			suggestions := cli.EnumCompletion(opt, "snake")
+
+			t.Then("the matching canonical value names are returned", func(t *bdd.T) {
+				require.That(t, suggestions).Field("Option").Eq([]string{"snake-case"})
+			})
+		})
+	})
+
+	bdd.Given(t, "an option of a non-enum type", func(t *bdd.T) {
+		var opt = opts.GetOption("name")
+
+		t.When("calling EnumCompletion()", func(t *bdd.T) {
+			suggestions := cli.EnumCompletion(opt, "")
+
+			t.Then("no suggestion is returned", func(t *bdd.T) {
+				require.That(t, suggestions).IsEmpty()
+			})
+		})
+	})
+}
+
+type optionalValueCmd struct {
+	compCmd
+	Format strcase.Format `opts:"-f, --format, optional-value:snake-case"`
+	Force  bool           `opts:"--force"`
+	Name   string         `opts:"arg:1, name:name"`
+}
+
+func TestCommandRunCompletionOptionalValue(t *testing.T) {
+	bdd.Given(t, "a command with an optional-value flag", func(t *bdd.T) {
+		var cmd = &cli.Command{
+			Handler: &optionalValueCmd{},
+		}
+
+		t.When("calling Run() with a partial value after the flag and '='", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--format=snake"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "--format=snake",
+				"COMP_INDEX": "1",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("matching values are suggested with the flag prefix", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).Eq([]string{"--format=snake-case"})
+			})
+		})
+
+		t.When("calling Run() with a partial argument after the bare flag", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--format", "comp"}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "comp",
+				"COMP_INDEX": "2",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("the flag does not consume the argument", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).IsSupersetOf(
+					[]string{"completion.go", "completion_test.go"})
+			})
+		})
+
+		t.When("calling Run() with a bool flag and '='", func(t *bdd.T) {
+			cmd.ProcessArgs = []string{"command-name", "--force="}
+			cmd.ProcessEnv = map[string]string{
+				"COMP_WORD":  "--force=",
+				"COMP_INDEX": "1",
+			}
+			cmd.Suggestions = nil
+			cmd.Run()
+
+			t.Then("bool values are suggested with the flag prefix", func(t *bdd.T) {
+				require.That(t, cmd.Suggestions).Eq([]string{"--force=true", "--force=false"})
+			})
+		})
+	})
+}
@@ -1,7 +1,11 @@
 package cli
 
 import (
+	"fmt"
+
 	"github.com/maargenton/go-errors"
+
+	"github.com/maargenton/go-cli/pkg/option"
 )
 
 // ErrHelpRequested is a sentinel error indicating that help was requested with
@@ -17,7 +21,92 @@ const ErrVersionRequested = errors.Sentinel("ErrVersionRequested")
 // evaluation
 const ErrCompletionScriptRequested = errors.Sentinel("ErrCompletionScriptRequested")
 
+// ErrZshCompletionScriptRequested is a sentinel error indicating the zsh
+// completion script was requested and should be printed to stdout for
+// evaluation
+const ErrZshCompletionScriptRequested = errors.Sentinel("ErrZshCompletionScriptRequested")
+
+// ErrFishCompletionScriptRequested is a sentinel error indicating the fish
+// completion script was requested and should be printed to stdout for
+// evaluation
+const ErrFishCompletionScriptRequested = errors.Sentinel("ErrFishCompletionScriptRequested")
+
+// ErrPowerShellCompletionScriptRequested is a sentinel error indicating the
+// PowerShell completion script was requested and should be printed to stdout
+// for evaluation
+const ErrPowerShellCompletionScriptRequested = errors.Sentinel("ErrPowerShellCompletionScriptRequested")
+
+// ErrShowConfigRequested is a sentinel error indicating that the effective
+// configuration of the command was requested and should be printed to stdout
+const ErrShowConfigRequested = errors.Sentinel("ErrShowConfigRequested")
+
 // ErrCompletionRequested is a sentinel error indicating that the command was
 // invoked in completion mode and that the completion options should be printed
 // out instead of running the command
 const ErrCompletionRequested = errors.Sentinel("ErrCompletionRequested")
+
+// ErrSchemaRequested is a sentinel error indicating that the JSON schema of the
+// command-line interface was requested and should be printed to stdout
+const ErrSchemaRequested = errors.Sentinel("ErrSchemaRequested")
+
+// ErrManPageRequested is a sentinel error indicating that the man page of the
+// command was requested and should be printed to stdout
+const ErrManPageRequested = errors.Sentinel("ErrManPageRequested")
+
+// UsageError is a custom error type wrapping errors caused by an invalid usage
+// of the command, as opposed to errors raised while running the command.
+// Usage errors terminate the process with exit code 2.
+type UsageError = option.UsageError
+
+// ErrInvalidCommand is a custom error type indicating that a sub-command name
+// was not recognized. `Suggestions` lists the known sub-commands with a name
+// close to the invalid one, if any.
+type ErrInvalidCommand struct {
+	Command     string
+	Suggestions []string
+}
+
+func (err *ErrInvalidCommand) Error() string {
+	return fmt.Sprintf("invalid command '%v'%v",
+		err.Command, option.FormatSuggestions(err.Suggestions))
+}
+
+// ExitCoder is implemented by errors that define the exit code of the process
+// when returned from `cmd.Run()`.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// ExitError is a custom error type carrying the exit code of the process along
+// with the error that caused it.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Exit returns an error that terminates the process with exit code `code`,
+// after printing the error `err`, if not nil. It is meant to be returned by
+// command handlers that need specific exit codes.
+func Exit(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+// Error returns the message of the underlying error, or an empty string if
+// there is none.
+func (err *ExitError) Error() string {
+	if err.Err == nil {
+		return ""
+	}
+	return err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *ExitError) Unwrap() error {
+	return err.Err
+}
+
+// ExitCode returns the exit code of the process.
+func (err *ExitError) ExitCode() int {
+	return err.Code
+}
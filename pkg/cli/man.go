@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+// ManPage returns a man page for the command in roff format, suitable for
+// `man(7)`, built from the description of the command, its options and
+// arguments, and its sub-commands. The page includes the NAME, SYNOPSIS,
+// DESCRIPTION, OPTIONS and ENVIRONMENT sections, the latter listing the
+// environment variables defined through `env:` tags, and a COMMANDS section for
+// commands defining sub-commands.
+func (cmd *Command) ManPage() (string, error) {
+	if err := cmd.initialize(); err != nil {
+		return "", err
+	}
+	cmd.addSpecialFlags()
+
+	var name = cmd.ProcessName
+	var version string
+	if vh := cmd.versionHandler(); vh != nil {
+		version = vh.Version()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %v 1 \"\" %v \"User Commands\"\n",
+		roffQuote(strings.ToUpper(name)), roffQuote(strings.TrimSpace(name+" "+version)))
+
+	fmt.Fprintf(&b, ".SH NAME\n")
+	var summary = strings.SplitN(strings.TrimSpace(cmd.Description), "\n", 2)[0]
+	if summary != "" {
+		fmt.Fprintf(&b, "%v \\- %v\n", roffEscape(name), roffEscape(summary))
+	} else {
+		fmt.Fprintf(&b, "%v\n", roffEscape(name))
+	}
+
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %v\n", roffEscape(name))
+	fmt.Fprintf(&b, "%v\n", roffEscape(strings.Join(cmd.usageArgs(), " ")))
+
+	if d := strings.TrimSpace(cmd.Description); d != "" {
+		fmt.Fprintf(&b, ".SH DESCRIPTION\n")
+		fmt.Fprintf(&b, "%v\n", roffParagraphs(d))
+	}
+
+	if options := cmd.manOptions(); len(options) != 0 {
+		fmt.Fprintf(&b, ".SH OPTIONS\n")
+		writeManList(&b, options)
+	}
+
+	var commands []*Command
+	if err := cmd.collectSubcommands(&commands); err != nil {
+		return "", err
+	}
+	if len(commands) != 0 {
+		fmt.Fprintf(&b, ".SH COMMANDS\n")
+		for _, sub := range commands {
+			fmt.Fprintf(&b, ".TP\n.B %v\n", roffEscape(
+				strings.TrimSpace(sub.ProcessName+" "+strings.Join(sub.usageArgs(), " "))))
+			if d := strings.TrimSpace(sub.Description); d != "" {
+				fmt.Fprintf(&b, "%v\n", roffParagraphs(d))
+			}
+			if options := sub.manOptions(); len(options) != 0 {
+				fmt.Fprintf(&b, ".RS\n")
+				writeManList(&b, options)
+				fmt.Fprintf(&b, ".RE\n")
+			}
+		}
+	}
+
+	var env []option.Description
+	for _, c := range append([]*Command{cmd}, commands...) {
+		env = append(env, c.manEnvironment()...)
+	}
+	if len(env) != 0 {
+		fmt.Fprintf(&b, ".SH ENVIRONMENT\n")
+		writeManList(&b, env)
+	}
+
+	return b.String(), nil
+}
+
+// collectSubcommands appends all the sub-commands of the command to `commands`,
+// recursively and in alphabetical order, each initialized with its full name.
+func (cmd *Command) collectSubcommands(commands *[]*Command) error {
+	for _, n := range cmd.subcommandNames() {
+		sub, err := cmd.subcommand([]string{n})
+		if err != nil {
+			return err
+		}
+		*commands = append(*commands, sub)
+		if err := sub.collectSubcommands(commands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manOptions returns the usage of the arguments with a description and of the
+// visible options of the command.
+func (cmd *Command) manOptions() (options []option.Description) {
+	var args = append([]*option.T{}, cmd.opts.Positional...)
+	if cmd.opts.Args != nil {
+		args = append(args, cmd.opts.Args)
+	}
+	for _, arg := range args {
+		if usage := arg.GetUsage(); usage.Description != "" {
+			options = append(options, usage)
+		}
+	}
+	for _, opt := range cmd.opts.Options {
+		// Special flags of sub-commands are documented on the root command
+		if !opt.Hidden && (opt.Type != option.Special || cmd.parent == nil) {
+			var usage = opt.GetUsage()
+			usage.Option = strings.TrimSpace(usage.Option)
+			options = append(options, usage)
+		}
+	}
+	return
+}
+
+// manEnvironment returns the environment variables of the command, each with
+// the option it sets and its description.
+func (cmd *Command) manEnvironment() (env []option.Description) {
+	var all = append([]*option.T{}, cmd.opts.Options...)
+	all = append(all, cmd.opts.Positional...)
+	if cmd.opts.Args != nil {
+		all = append(all, cmd.opts.Args)
+	}
+	for _, opt := range all {
+		if opt.Env == "" || opt.Hidden {
+			continue
+		}
+		var d = fmt.Sprintf("Sets %v", opt.Name())
+		if cmd.parent != nil {
+			d = fmt.Sprintf("Sets %v of '%v'", opt.Name(), cmd.ProcessName)
+		}
+		if opt.Description != "" {
+			d += ": " + opt.Description
+		}
+		env = append(env, option.Description{Option: opt.Env, Description: d})
+	}
+	return
+}
+
+// writeManList writes a list of tagged paragraphs, one for each option.
+func writeManList(b *strings.Builder, list []option.Description) {
+	for _, d := range list {
+		fmt.Fprintf(b, ".TP\n.B %v\n", roffEscape(d.Option))
+		if d.Description != "" {
+			fmt.Fprintf(b, "%v\n", roffParagraphs(d.Description))
+		}
+	}
+}
+
+// roffParagraphs escapes a multi-line text for roff, separating paragraphs at
+// blank lines and preserving explicit line breaks.
+func roffParagraphs(s string) string {
+	var lines []string
+	var blank = false
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = true
+			continue
+		}
+		if blank {
+			lines = append(lines, ".PP")
+		} else if len(lines) != 0 {
+			lines = append(lines, ".br")
+		}
+		blank = false
+		lines = append(lines, roffEscape(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffEscape escapes a single line of text for roff: backslashes and dashes
+// are escaped, and a line starting with a control character is protected.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// roffQuote returns an escaped and quoted argument for a roff request.
+func roffQuote(s string) string {
+	return `"` + strings.ReplaceAll(roffEscape(s), `"`, `""`) + `"`
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+// Markdown returns a reference documentation of the command in Markdown
+// format, with a synopsis, the description of the positional arguments and a
+// table of options listing their flags, value name, default value, environment
+// variable and description. Each sub-command is documented in its own section
+// following the one of the command, with a heading level matching its depth in
+// the command tree.
+func (cmd *Command) Markdown() (string, error) {
+	if err := cmd.initialize(); err != nil {
+		return "", err
+	}
+	cmd.addSpecialFlags()
+
+	var b strings.Builder
+	cmd.writeMarkdown(&b)
+
+	var commands []*Command
+	if err := cmd.collectSubcommands(&commands); err != nil {
+		return "", err
+	}
+	for _, sub := range commands {
+		b.WriteString("\n")
+		sub.writeMarkdown(&b)
+	}
+	return b.String(), nil
+}
+
+// writeMarkdown writes the documentation of the command, with a title at the
+// heading level matching the depth of the command and sections one level below.
+func (cmd *Command) writeMarkdown(b *strings.Builder) {
+	var h = "#"
+	for c := cmd.parent; c != nil; c = c.parent {
+		h += "#"
+	}
+	fmt.Fprintf(b, "%v %v\n\n", h, cmd.ProcessName)
+	if d := strings.TrimSpace(cmd.Description); d != "" {
+		fmt.Fprintf(b, "%v\n\n", d)
+	}
+
+	fmt.Fprintf(b, "%v# Synopsis\n\n", h)
+	fmt.Fprintf(b, "```\n%v %v\n```\n", cmd.ProcessName, strings.Join(cmd.usageArgs(), " "))
+
+	var args = append([]*option.T{}, cmd.opts.Positional...)
+	if cmd.opts.Args != nil {
+		args = append(args, cmd.opts.Args)
+	}
+	if len(args) != 0 {
+		fmt.Fprintf(b, "\n%v# Arguments\n\n", h)
+		fmt.Fprintf(b, "| Argument | Description |\n")
+		fmt.Fprintf(b, "| --- | --- |\n")
+		for _, arg := range args {
+			fmt.Fprintf(b, "| %v | %v |\n",
+				markdownCode(arg.GetUsage().Option), markdownCell(arg.GetUsage().Description))
+		}
+	}
+
+	var options []*option.T
+	for _, opt := range cmd.opts.Options {
+		if !opt.Hidden {
+			options = append(options, opt)
+		}
+	}
+	if len(options) != 0 {
+		fmt.Fprintf(b, "\n%v# Options\n\n", h)
+		fmt.Fprintf(b, "| Flags | Value | Default | Environment | Description |\n")
+		fmt.Fprintf(b, "| --- | --- | --- | --- | --- |\n")
+		for _, opt := range options {
+			var flags []string
+			for _, f := range strings.Split(opt.FlagsUsage(), ", ") {
+				flags = append(flags, markdownCode(f))
+			}
+			fmt.Fprintf(b, "| %v | %v | %v | %v | %v |\n",
+				strings.Join(flags, ", "),
+				markdownCode(markdownValue(opt)),
+				markdownCode(opt.Default),
+				markdownCode(opt.Env),
+				markdownCell(markdownDescription(opt)))
+		}
+	}
+
+	if len(cmd.Subcommands) != 0 {
+		fmt.Fprintf(b, "\n%v# Commands\n\n", h)
+		fmt.Fprintf(b, "| Command | Description |\n")
+		fmt.Fprintf(b, "| --- | --- |\n")
+		for _, name := range cmd.subcommandNames() {
+			fmt.Fprintf(b, "| %v | %v |\n",
+				markdownCode(name), markdownCell(cmd.Subcommands[name].Description))
+		}
+	}
+}
+
+// markdownValue returns the value expected by the option as displayed in the
+// usage, within brackets if the value is optional.
+func markdownValue(opt *option.T) string {
+	var v = opt.ValueUsage()
+	if v != "" && opt.OptionalValue {
+		if opt.Long != "" {
+			return "[=" + v + "]"
+		}
+		return "[" + v + "]"
+	}
+	return v
+}
+
+// markdownDescription returns the description of the option followed by its
+// constraints, as displayed in the usage. Default values and environment
+// variables have their own columns.
+func markdownDescription(opt *option.T) string {
+	var c = opt.ConstraintsUsage()
+	if c == "" {
+		return opt.Description
+	}
+	if opt.Description == "" {
+		return c
+	}
+	return opt.Description + ", " + c
+}
+
+// markdownCode formats a table cell as inline code, or returns an empty string
+// if `s` is empty.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(s, "|", `\|`) + "`"
+}
+
+// markdownCell escapes a text for use in a table cell, where line breaks and
+// pipes are not allowed.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", `\|`)
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "\n", " <br> ")), " ")
+}
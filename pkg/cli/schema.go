@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+// SchemaVersion is the version of the document format returned by
+// `Command.Schema()`, incremented on incompatible changes.
+const SchemaVersion = 1
+
+// Schema is a machine-readable representation of a command, its options and
+// sub-commands, suitable for JSON serialization.
+type Schema struct {
+	SchemaVersion int    `json:"schemaVersion,omitempty"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	Version       string `json:"version,omitempty"`
+
+	option.Schema
+	Subcommands []Schema `json:"subcommands,omitempty"`
+}
+
+// Schema returns a machine-readable representation of the command and all its
+// sub-commands, used to generate documentation, wrappers or user interfaces
+// without parsing the usage output.
+func (cmd *Command) Schema() (*Schema, error) {
+	schema, err := cmd.schema(cmd.ProcessName)
+	if err != nil {
+		return nil, err
+	}
+	schema.SchemaVersion = SchemaVersion
+	return schema, nil
+}
+
+func (cmd *Command) schema(name string) (*Schema, error) {
+	if err := cmd.initialize(); err != nil {
+		return nil, err
+	}
+	cmd.addSpecialFlags()
+
+	var schema = &Schema{
+		Name:        name,
+		Description: cmd.Description,
+		Schema:      cmd.opts.Schema(),
+	}
+	if vh := cmd.versionHandler(); vh != nil {
+		schema.Version = vh.Version()
+	}
+
+	for _, n := range cmd.subcommandNames() {
+		sub, err := cmd.subcommand([]string{n})
+		if err != nil {
+			return nil, err
+		}
+		subSchema, err := sub.schema(n)
+		if err != nil {
+			return nil, err
+		}
+		schema.Subcommands = append(schema.Subcommands, *subSchema)
+	}
+	return schema, nil
+}
@@ -0,0 +1,20 @@
+Register-ArgumentCompleter -Native -CommandName 'command-name' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |
+        ForEach-Object { $_.Extent.Text })
+    $index = $words.Count
+    if ($wordToComplete -ne '') { $index = $words.Count - 1 }
+    $env:COMP_SHELL = 'pwsh'
+    $env:COMP_INDEX = $index
+    $env:COMP_WORD = $wordToComplete
+    $suggestions = & $words[0] @($words | Select-Object -Skip 1)
+    Remove-Item Env:COMP_SHELL, Env:COMP_INDEX, Env:COMP_WORD
+    $suggestions | ForEach-Object {
+        $value, $desc = $_ -split "`t", 2
+        $type = 'ParameterValue'
+        if ($value.StartsWith('-')) { $type = 'ParameterName' }
+        if (-not $desc) { $desc = $value }
+        [System.Management.Automation.CompletionResult]::new($value, $value, $type, $desc)
+    }
+}
@@ -0,0 +1,59 @@
+package cli
+
+// Style is a list of ANSI SGR parameters separated by semicolons, e.g. "1;31"
+// for bold red, applied to a piece of text when colors are enabled. An empty
+// style leaves the text unchanged.
+type Style string
+
+// Apply returns `s` wrapped in the ANSI escape sequences that enable the style
+// and reset all attributes. Empty strings are returned unchanged.
+func (style Style) Apply(s string) string {
+	if style == "" || s == "" {
+		return s
+	}
+	return "\x1b[" + string(style) + "m" + s + "\x1b[0m"
+}
+
+// Theme defines the styles used to colorize the usage and error messages of a
+// command when colors are enabled.
+type Theme struct {
+	Heading Style // section headings of the usage
+	Flag    Style // flag names
+	Value   Style // value placeholders and positional argument names
+	Error   Style // prefix of error messages
+}
+
+// DefaultTheme is the theme used by commands that do not define their own.
+var DefaultTheme = Theme{
+	Heading: "1",
+	Flag:    "36",
+	Value:   "33",
+	Error:   "1;31",
+}
+
+// ColorEnabled returns true if colors should be used for an output stream,
+// given the environment `env` and whether the stream is a terminal. Following
+// common conventions, a non-empty `NO_COLOR` variable disables colors, and a
+// `CLICOLOR_FORCE` variable set to anything other than "0" enables them even if
+// the stream is not a terminal.
+func ColorEnabled(env map[string]string, terminal bool) bool {
+	if env["NO_COLOR"] != "" {
+		return false
+	}
+	if v, ok := env["CLICOLOR_FORCE"]; ok && v != "" && v != "0" {
+		return true
+	}
+	return terminal
+}
+
+// theme returns the styles to apply to the output of the command, or an empty
+// theme if colors are not enabled.
+func (cmd *Command) theme() Theme {
+	if !cmd.Color {
+		return Theme{}
+	}
+	if cmd.Theme != nil {
+		return *cmd.Theme
+	}
+	return DefaultTheme
+}
@@ -1,10 +1,11 @@
 package enum
 
 type Value struct {
-	Name     string
-	GoName   string
-	AltNames []string
-	Value    interface{}
+	Name        string
+	GoName      string
+	AltNames    []string
+	Value       interface{}
+	Description string
 }
 
 type Type interface {
@@ -1,7 +1,9 @@
 package enumer
 
 import (
+	"go/ast"
 	"go/constant"
+	"go/token"
 	"go/types"
 	"sort"
 	"strings"
@@ -17,6 +19,7 @@ func ExtractEnums(pkg *packages.Package) (*PkgEnums, error) {
 		PkgPath: pkg.PkgPath,
 	}
 
+	var comments = extractConstComments(pkg)
 	var pkgTypes = make(map[*types.Named][]*types.Const)
 	var scope = pkg.Types.Scope()
 	var names = scope.Names()
@@ -61,8 +64,9 @@ func ExtractEnums(pkg *packages.Package) (*PkgEnums, error) {
 				continue
 			}
 			var value = EnumValueDef{
-				Name: v.Name(),
-				def:  v,
+				Name:        v.Name(),
+				Description: comments[v.Name()],
+				def:         v,
 			}
 
 			if v, ok := constant.Int64Val(v.Val()); ok {
@@ -87,6 +91,43 @@ func ExtractEnums(pkg *packages.Package) (*PkgEnums, error) {
 	return enums, nil
 }
 
+// extractConstComments returns the doc or line comment attached to each
+// top-level constant of the package, indexed by constant name. Doc comments
+// take precedence over line comments; the doc comment of an ungrouped constant
+// declaration is attached to the declaration itself.
+func extractConstComments(pkg *packages.Package) map[string]string {
+	var comments = make(map[string]string)
+	for _, f := range pkg.Syntax {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				var text string
+				if spec.Doc != nil {
+					text = spec.Doc.Text()
+				} else if decl.Doc != nil && !decl.Lparen.IsValid() {
+					text = decl.Doc.Text()
+				} else if spec.Comment != nil {
+					text = spec.Comment.Text()
+				}
+				text = strings.Join(strings.Fields(text), " ")
+				for _, name := range spec.Names {
+					if text != "" {
+						comments[name.Name] = text
+					}
+				}
+			}
+		}
+	}
+	return comments
+}
+
 func ExtractValues(t *EnumType, f strcase.Format) error {
 	var m = make(map[interface{}][]EnumValueDef)
 	var v []interface{}
@@ -105,6 +146,12 @@ func ExtractValues(t *EnumType, f strcase.Format) error {
 			GoName: defs[0].Name,
 			Value:  vv,
 		}
+		for _, d := range defs {
+			if d.Description != "" {
+				value.Description = d.Description
+				break
+			}
+		}
 
 		var parts = strcase.Split(value.GoName)
 		var altParts = strcase.FilterParts(parts, typeParts)
@@ -0,0 +1,113 @@
+package enumer
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/maargenton/go-testpredicate/pkg/bdd"
+	"github.com/maargenton/go-testpredicate/pkg/require"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/maargenton/go-cli/pkg/strcase"
+)
+
+const commentedEnumSrc = `package sample
+
+type Algorithm int
+
+const (
+	// RSA signatures with
+	// 2048-bit keys
+	Rsa2048 Algorithm = iota
+	Rsa4096                // RSA signatures with 4096-bit keys
+	Ed25519                // Ed25519 signatures
+	Ed25519Alias = Ed25519 // Alias of Ed25519
+	Undocumented Algorithm = 10
+)
+
+// Version is not an enum value
+const Version = "v1"
+`
+
+func loadTestPackage(t *bdd.T, src string) *packages.Package {
+	var fset = token.NewFileSet()
+	f, err := parser.ParseFile(fset, "sample.go", src, parser.ParseComments)
+	require.That(t, err).IsNil()
+
+	var files = []*ast.File{f}
+	pkg, err := (&types.Config{}).Check("example.com/sample", fset, files, nil)
+	require.That(t, err).IsNil()
+
+	return &packages.Package{
+		Name:    pkg.Name(),
+		PkgPath: pkg.Path(),
+		Fset:    fset,
+		Syntax:  files,
+		Types:   pkg,
+	}
+}
+
+func TestExtractEnumsDescriptions(t *testing.T) {
+	bdd.Given(t, "a package with commented enum constants", func(t *bdd.T) {
+		var pkg = loadTestPackage(t, commentedEnumSrc)
+
+		t.When("calling extractConstComments()", func(t *bdd.T) {
+			var comments = extractConstComments(pkg)
+
+			t.Then("doc and line comments are indexed by constant name", func(t *bdd.T) {
+				require.That(t, comments).Eq(map[string]string{
+					"Rsa2048":      "RSA signatures with 2048-bit keys",
+					"Rsa4096":      "RSA signatures with 4096-bit keys",
+					"Ed25519":      "Ed25519 signatures",
+					"Ed25519Alias": "Alias of Ed25519",
+					"Version":      "Version is not an enum value",
+				})
+			})
+		})
+
+		t.When("calling ExtractEnums() and ExtractValues()", func(t *bdd.T) {
+			enums, err := ExtractEnums(pkg)
+			require.That(t, err).IsNil()
+			require.That(t, enums.Types).Length().Eq(1)
+
+			var enum = enums.Types[0]
+			err = ExtractValues(&enum, strcase.FilteredHyphenCase)
+			require.That(t, err).IsNil()
+
+			t.Then("each value carries the description of its first definition", func(t *bdd.T) {
+				require.That(t, enum.Values).Field("GoName").Eq([]string{
+					"Rsa2048", "Rsa4096", "Ed25519", "Undocumented",
+				})
+				require.That(t, enum.Values).Field("Description").Eq([]string{
+					"RSA signatures with 2048-bit keys",
+					"RSA signatures with 4096-bit keys",
+					"Ed25519 signatures",
+					"",
+				})
+			})
+
+			t.Then("the generated code includes the descriptions", func(t *bdd.T) {
+				var buf bytes.Buffer
+				err := enumerTemplate.Execute(&buf, &PkgEnums{
+					PkgName: enums.PkgName,
+					PkgPath: enums.PkgPath,
+					Types:   []EnumType{enum},
+				})
+				require.That(t, err).IsNil()
+
+				var code = buf.String()
+				require.That(t, code).Contains(
+					`Description: "RSA signatures with 2048-bit keys",`)
+				require.That(t, code).Contains(
+					`Description: "Ed25519 signatures",`)
+				require.That(t, code).Contains(
+					"Name:     \"undocumented\",\n" +
+						"\t\tAltNames: []string{")
+			})
+		})
+	})
+}
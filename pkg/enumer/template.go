@@ -48,6 +48,9 @@ var {{.Name}}Values = []enum.Value{
 		Name:     {{.Name | printf "%q"}},
 		AltNames: []string{ {{range .AltNames -}}{{. | printf "%q"}},{{end -}} },
 		Value:    {{.GoName}},
+		{{if .Description -}}
+		Description: {{.Description | printf "%q"}},
+		{{end -}}
 	},
 	{{end -}}
 }
@@ -28,17 +28,19 @@ type EnumType struct {
 
 // EnumValueDef records the definition of an enumerated value
 type EnumValueDef struct {
-	Name  string
-	Value interface{}
-	def   *types.Const
+	Name        string
+	Value       interface{}
+	Description string
+	def         *types.Const
 }
 
 // EnumValue records all the details of an enumerated value necessary to produce
 // the generated code output, including all alternate names for the definition
 // of one value.
 type EnumValue struct {
-	GoName string
-	Value  interface{}
+	GoName      string
+	Value       interface{}
+	Description string
 
 	Name           string
 	AltNames       []string
@@ -6,19 +6,24 @@ import (
 
 // Completion records a set of completion suggestions, including usable flags,
 // values for a specific flag and / or values for next remaining argument.
+// Values are filled by the caller, each with an optional description.
 type Completion struct {
-	Options   []string
-	OptValues []string
-	ArgValues []string
-	OptRef    *T
-	ArgRef    *T
+	Options            []string
+	OptionDescriptions []string // description of each of Options, from the `desc` tag
+	OptValues          []Description
+	ArgValues          []Description
+	OptRef             *T
+	ArgRef             *T
+	OptPrefix          string // set to `--flag=` when completing a value in that form
 }
 
 // GetCompletion evaluate the list of command line arguments `args` in the
 // context of the receiver, and determines a list of completion suggestions for
 // the `partial` argument given. The result is a partially filled `Completion`
 // object with either a list of `Options` or one of `Opt` or `Arg` set the the
-// `option.T` whose value needs to be completed.
+// `option.T` whose value needs to be completed. If `partial` is of the form
+// `--flag=value`, `OptPrefix` is set to `--flag=`, and only the part of
+// `partial` past the prefix should be completed.
 func (opts *Set) GetCompletion(args []string, partial string) (r Completion) {
 
 	// Evaluate commandline arguments, discarding values
@@ -30,9 +35,14 @@ func (opts *Set) GetCompletion(args []string, partial string) (r Completion) {
 			opt = nil // swallow value
 		} else if strings.HasPrefix(arg, "--") {
 			opt = opts.GetOption(arg[2:])
+			if opt == nil {
+				if neg := opts.negatedOption(arg[2:]); neg != nil {
+					usedOptions[neg] = struct{}{}
+				}
+			}
 			if opt != nil {
 				usedOptions[opt] = struct{}{}
-				if opt.Type == Bool || opt.Type == Special {
+				if opt.Type == Bool || opt.Type == Special || opt.Type == Count || opt.OptionalValue {
 					opt = nil // no value expected
 				}
 			}
@@ -42,11 +52,11 @@ func (opts *Set) GetCompletion(args []string, partial string) (r Completion) {
 				opt = opts.GetOption(string(c))
 				if opt != nil {
 					usedOptions[opt] = struct{}{}
-					if opt.Type == Bool || opt.Type == Special {
+					if opt.Type == Bool || opt.Type == Special || opt.Type == Count {
 						opt = nil // no value expected
 					} else {
 						value := arg[i+1:]
-						if len(value) > 0 {
+						if len(value) > 0 || opt.OptionalValue {
 							opt = nil
 						}
 						break
@@ -63,29 +73,63 @@ func (opts *Set) GetCompletion(args []string, partial string) (r Completion) {
 		return r
 	}
 
+	if i := strings.IndexByte(partial, '='); strings.HasPrefix(partial, "--") && i >= 0 {
+		opt = opts.GetOption(partial[2:i])
+		if opt != nil && opt.Type != Special && opt.Type != Count {
+			r.OptRef = opt
+			r.OptPrefix = partial[:i+1]
+		}
+		return r
+	}
+
 	var nonExclusiveUsed = len(remainingArgs) > 0
 	for o := range usedOptions {
-		if o.Type == Special {
+		if o.Type == Special && !o.Deferred {
 			// Exclusive flag has been used, nothing more to suggest
 			return r
 		}
 		nonExclusiveUsed = true
 	}
+	var excluded = opts.excludedOptions(usedOptions)
 	for _, o := range opts.Options {
-		if _, used := usedOptions[o]; !used || o.Type == Slice {
+		if _, ok := excluded[o]; ok || o.Hidden {
+			continue
+		}
+		if _, used := usedOptions[o]; !used || o.Type == Slice || o.Type == Map || o.Type == Count {
 			if o.Type == Special && nonExclusiveUsed {
 				// Non-exclusive flag has been used, skip special flags
 				continue
 			}
 			r.Options = append(r.Options, o.Name())
+			r.OptionDescriptions = append(r.OptionDescriptions, o.Description)
+			if o.Negatable {
+				r.Options = append(r.Options, "--no-"+o.Long)
+				r.OptionDescriptions = append(r.OptionDescriptions, o.Description)
+			}
 		}
 	}
 
-	if len(remainingArgs) < len(opts.Positional) {
-		r.ArgRef = opts.Positional[len(remainingArgs)]
-	} else if opts.Args != nil {
-		r.ArgRef = opts.Args
-	}
-
+	r.ArgRef = opts.argumentSlot(len(remainingArgs))
 	return r
 }
+
+// argumentSlot returns the positional argument receiving the non-option
+// argument at index `n`, assuming no other argument follows. A slice positional
+// argument receives all subsequent arguments until its maximum count is
+// reached, if any.
+func (opts *Set) argumentSlot(n int) *T {
+	for _, opt := range opts.Positional {
+		if opt.Type == Slice {
+			if opt.MaxCount == 0 || n < opt.MaxCount {
+				return opt
+			}
+			n -= opt.MaxCount
+			continue
+		}
+		if n == 0 {
+			return opt
+		}
+		n--
+	}
+	return opts.Args
+}
@@ -46,6 +46,15 @@ func TestGetCompletion(t *testing.T) {
 			t.Run("then available options are listed", func(t *testing.T) {
 				require.That(t, completion.Options).Length().Eq(5)
 			})
+			t.Run("then options are listed with their description", func(t *testing.T) {
+				require.That(t, completion.OptionDescriptions).Length().Eq(5)
+				for i, o := range completion.Options {
+					if o == "--timestamp" {
+						require.That(t, completion.OptionDescriptions[i]).Eq(
+							"prefix every line with elapsed time")
+					}
+				}
+			})
 		})
 
 		t.Run("when calling GetCompletion() with flag expecting a value", func(t *testing.T) {
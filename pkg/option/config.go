@@ -0,0 +1,365 @@
+package option
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the content of a configuration file decoded as a tree of
+// values, along with the line where each key is defined, used to report
+// errors. A Config can also represent a section of a configuration file, as
+// returned by `Section()`.
+type Config struct {
+	Filename string
+
+	values map[string]interface{}
+	lines  map[string]int
+	path   []string
+}
+
+// LoadConfig reads and decodes the configuration file `filename`. The format
+// of the file is determined by its extension: `.json` for JSON, `.toml` for
+// TOML, and YAML otherwise.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("while loading configuration file, %w", err)
+	}
+	return ParseConfig(filename, data)
+}
+
+// ParseConfig decodes the content of a configuration file named `filename`.
+// The name is used only to determine the format of the content and to report
+// errors. Invalid content is reported as `*UsageError`.
+func ParseConfig(filename string, data []byte) (*Config, error) {
+	var c = &Config{
+		Filename: filename,
+		values:   make(map[string]interface{}),
+		lines:    make(map[string]int),
+	}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		err = c.parseJSON(data)
+	case ".toml":
+		err = c.parseTOML(data)
+	default:
+		err = c.parseYAML(data)
+	}
+	if err != nil {
+		return nil, &UsageError{Err: err}
+	}
+	return c, nil
+}
+
+// Section returns the configuration contained in the table or mapping named
+// `name`, or nil if there is no such section. It is safe to call on a nil
+// receiver.
+func (c *Config) Section(name string) *Config {
+	if c == nil {
+		return nil
+	}
+	values, ok := c.values[name].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return &Config{
+		Filename: c.Filename,
+		values:   values,
+		lines:    c.lines,
+		path:     c.keyPath(name),
+	}
+}
+
+// Lookup returns the value defined for `key`, along with the line where it is
+// defined in the configuration file, if known.
+func (c *Config) Lookup(key string) (value interface{}, line int, ok bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	value, ok = c.values[key]
+	line = c.lines[strings.Join(c.keyPath(key), ".")]
+	return
+}
+
+func (c *Config) keyPath(key string) []string {
+	return append(c.path[:len(c.path):len(c.path)], key)
+}
+
+// errorf returns an error prefixed with the filename and the line number, if
+// known.
+func (c *Config) errorf(line int, format string, args ...interface{}) error {
+	if line > 0 {
+		return fmt.Errorf("%v:%v: %w", c.Filename, line, fmt.Errorf(format, args...))
+	}
+	return fmt.Errorf("%v: %w", c.Filename, fmt.Errorf(format, args...))
+}
+
+// ---------------------------------------------------------------------------
+// Applying configuration values to an option set
+// ---------------------------------------------------------------------------
+
+// ApplyConfig scans through a parsed option set and applies the values defined
+// in the configuration `c` to the fields of the target struct value. Values are
+// looked up by the key specified with the `config-key` tag, or by the long name
+// of the flag. Keys that do not match any option are ignored, and invalid values
+// are reported as `*UsageError`.
+func (opts *Set) ApplyConfig(c *Config) error {
+	if c == nil {
+		return nil
+	}
+	for _, opt := range opts.Options {
+		var key = opt.ConfigKey
+		if key == "" {
+			key = opt.Long
+		}
+		if key == "" || opt.Type == Special || opt.ConfigFile {
+			continue
+		}
+		v, line, ok := c.Lookup(key)
+		if !ok {
+			continue
+		}
+		if err := opt.setConfigValue(v); err != nil {
+			return &UsageError{Err: c.errorf(line, "%w", err)}
+		}
+		opt.source = Source{Kind: ConfigSource, Name: c.Filename, Line: line}
+	}
+	return nil
+}
+
+// ConfigFilename returns the name of the configuration file specified by the
+// option tagged with `config-file`, if any, looking first at the command-line
+// arguments `args`, then at the environment variable associated with the
+// option, then at its default value. `explicit` is set to true unless the name
+// is the default value.
+func (opts *Set) ConfigFilename(args []string, env map[string]string) (filename string, explicit bool) {
+	var opt *T
+	for _, o := range opts.Options {
+		if o.ConfigFile {
+			opt = o
+			break
+		}
+	}
+	if opt == nil {
+		return "", false
+	}
+	if v, ok := opts.scanArgValue(opt, args); ok {
+		return strings.TrimSpace(v), true
+	}
+	if opt.Env != "" {
+		if v, ok := env[opt.Env]; ok {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return opt.Default, false
+}
+
+// scanArgValue returns the last value given to `target` in `args`, without
+// applying any of the arguments. Arguments are scanned the same way as by
+// `ApplyArgs()`, up to the first invalid flag, if any.
+func (opts *Set) scanArgValue(target *T, args []string) (value string, found bool) {
+	opts.scanArgs(args, func(t argToken) error {
+		if t.opt == target && t.hasValue {
+			value, found = t.value, true
+		}
+		return nil
+	})
+	return
+}
+
+// setConfigValue applies a value decoded from a configuration file to the
+// option. Lists are accepted only for slice and map options, tables only for
+// map options, and both replace any previous value.
+func (opt *T) setConfigValue(v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if opt.Type != Map {
+			return fmt.Errorf("invalid table value for '%v'", opt.Name())
+		}
+		if err := opt.SetValue(""); err != nil {
+			return err
+		}
+		var fv = opt.opts.target.FieldByIndex(opt.Index)
+		for k, vv := range v {
+			if err := opt.setMapEntry(fv, k, formatConfigValue(vv)); err != nil {
+				return fmt.Errorf("failed to set value for '%v': %w", opt.Name(), err)
+			}
+		}
+		return nil
+
+	case []interface{}:
+		if opt.Type != Slice && opt.Type != Map {
+			return fmt.Errorf("invalid list value for non-slice '%v'", opt.Name())
+		}
+		if err := opt.SetValue(""); err != nil {
+			return err
+		}
+		for _, vv := range v {
+			if err := opt.SetValue(formatConfigValue(vv)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if opt.Type == Slice || opt.Type == Map {
+		if err := opt.SetValue(""); err != nil {
+			return err
+		}
+	}
+	return opt.SetValue(formatConfigValue(v))
+}
+
+func formatConfigValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// ---------------------------------------------------------------------------
+// Format specific decoding
+// ---------------------------------------------------------------------------
+
+func (c *Config) parseYAML(data []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("%v: %w", c.Filename, err)
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	var doc = root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return c.errorf(doc.Line, "invalid configuration, mapping expected at top level")
+	}
+	if err := doc.Decode(&c.values); err != nil {
+		return fmt.Errorf("%v: %w", c.Filename, err)
+	}
+	c.recordYAMLLines(doc, nil)
+	return nil
+}
+
+func (c *Config) recordYAMLLines(n *yaml.Node, path []string) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		var k = n.Content[i]
+		var p = append(path[:len(path):len(path)], k.Value)
+		c.lines[strings.Join(p, ".")] = k.Line
+		c.recordYAMLLines(n.Content[i+1], p)
+	}
+}
+
+func (c *Config) parseJSON(data []byte) error {
+	var dec = json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&c.values); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) {
+			return c.errorf(lineAtOffset(data, syntaxErr.Offset), "%w", err)
+		}
+		if errors.As(err, &typeErr) {
+			return c.errorf(lineAtOffset(data, typeErr.Offset),
+				"invalid configuration, object expected at top level")
+		}
+		return fmt.Errorf("%v: %w", c.Filename, err)
+	}
+
+	dec = json.NewDecoder(bytes.NewReader(data))
+	c.recordJSONLines(dec, data, nil)
+	return nil
+}
+
+func (c *Config) recordJSONLines(dec *json.Decoder, data []byte, path []string) {
+	tok, err := dec.Token()
+	if err != nil {
+		return
+	}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return
+			}
+			var key, _ = tok.(string)
+			var p = append(path[:len(path):len(path)], key)
+			c.lines[strings.Join(p, ".")] = lineAtOffset(data, dec.InputOffset())
+			c.recordJSONLines(dec, data, p)
+		}
+		dec.Token()
+	case json.Delim('['):
+		for dec.More() {
+			c.recordJSONLines(dec, data, path)
+		}
+		dec.Token()
+	}
+}
+
+func lineAtOffset(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte{'\n'}) + 1
+}
+
+func (c *Config) parseTOML(data []byte) error {
+	if err := toml.Unmarshal(data, &c.values); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			var line, _ = decodeErr.Position()
+			return c.errorf(line, "%w", err)
+		}
+		return fmt.Errorf("%v: %w", c.Filename, err)
+	}
+
+	var p unstable.Parser
+	var table []string
+	p.Reset(data)
+	for p.NextExpression() {
+		var e = p.Expression()
+		if e.Kind != unstable.Table && e.Kind != unstable.ArrayTable && e.Kind != unstable.KeyValue {
+			continue
+		}
+
+		var path []string
+		if e.Kind == unstable.KeyValue {
+			path = table[:len(table):len(table)]
+		}
+		var line int
+		var it = e.Key()
+		for it.Next() {
+			var n = it.Node()
+			if line == 0 {
+				line = p.Shape(n.Raw).Start.Line
+			}
+			path = append(path, string(n.Data))
+		}
+		if e.Kind != unstable.KeyValue {
+			table = path
+		}
+		c.lines[strings.Join(path, ".")] = line
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package option_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maargenton/go-testpredicate/pkg/bdd"
+	"github.com/maargenton/go-testpredicate/pkg/require"
+
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+type configCommand struct {
+	Config  string        `opts:"-c, --config, config-file, env:CONFIG, default:app.yaml"`
+	Port    int           `opts:"-p, --port"`
+	Timeout time.Duration `opts:"--timeout, config-key:request-timeout"`
+	Tags    []string      `opts:"-t, --tag, default:a"`
+	Verbose bool          `opts:"-v, --verbose"`
+	Name    *string       `opts:"-n"`
+}
+
+var configTestData = []struct {
+	filename string
+	content  string
+}{
+	{"config.yaml", "" +
+		"port: 8080\n" +
+		"request-timeout: 5s\n" +
+		"tag: [b, c]\n" +
+		"verbose: true\n" +
+		"n: ignored\n" +
+		"db:\n" +
+		"  host: localhost\n"},
+	{"config.json", `{
+		"port": 8080,
+		"request-timeout": "5s",
+		"tag": ["b", "c"],
+		"verbose": true,
+		"n": "ignored",
+		"db": { "host": "localhost" }
+	}`},
+	{"config.toml", "" +
+		"port = 8080\n" +
+		"request-timeout = \"5s\"\n" +
+		"tag = [\"b\", \"c\"]\n" +
+		"verbose = true\n" +
+		"n = \"ignored\"\n" +
+		"[db]\n" +
+		"host = \"localhost\"\n"},
+}
+
+func TestApplyConfig(t *testing.T) {
+	for _, tc := range configTestData {
+		bdd.Given(t, "a "+tc.filename+" configuration", func(t *bdd.T) {
+			config, err := option.ParseConfig(tc.filename, []byte(tc.content))
+			require.That(t, err).IsNil()
+
+			var cmd configCommand
+			optionSet, err := option.NewOptionSet(&cmd)
+			require.That(t, err).IsNil()
+			require.That(t, optionSet.ApplyDefaults()).IsNil()
+
+			t.When("calling ApplyConfig()", func(t *bdd.T) {
+				err := optionSet.ApplyConfig(config)
+
+				t.Then("values are applied by long name or config key", func(t *bdd.T) {
+					require.That(t, err).IsNil()
+					require.That(t, cmd.Port).Eq(8080)
+					require.That(t, cmd.Timeout).Eq(5 * time.Second)
+					require.That(t, cmd.Verbose).IsTrue()
+				})
+				t.Then("list values replace the default value", func(t *bdd.T) {
+					require.That(t, cmd.Tags).Eq([]string{"b", "c"})
+				})
+				t.Then("options without long name are not configurable", func(t *bdd.T) {
+					require.That(t, cmd.Name).IsNil()
+				})
+			})
+
+			t.When("looking up a key in a section", func(t *bdd.T) {
+				v, line, ok := config.Section("db").Lookup("host")
+
+				t.Then("the value and its line are returned", func(t *bdd.T) {
+					require.That(t, ok).IsTrue()
+					require.That(t, v).Eq("localhost")
+					require.That(t, line).Eq(7)
+				})
+			})
+
+			t.When("looking up a missing section", func(t *bdd.T) {
+				_, _, ok := config.Section("none").Lookup("host")
+
+				t.Then("no value is found", func(t *bdd.T) {
+					require.That(t, ok).IsFalse()
+				})
+			})
+		})
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	var errorTestData = []struct {
+		filename string
+		content  string
+		err      string
+	}{
+		{"config.yaml", "verbose: true\nport: abc\n", "config.yaml:2: failed to set value for '--port'"},
+		{"config.json", "{\n  \"verbose\": true,\n  \"port\": \"abc\"\n}", "config.json:3: failed to set value for '--port'"},
+		{"config.toml", "verbose = true\nport = \"abc\"\n", "config.toml:2: failed to set value for '--port'"},
+		{"config.yaml", "verbose: true\nport: [1, 2]\n", "config.yaml:2: invalid list value for non-slice '--port'"},
+		{"config.toml", "verbose = true\n[port]\nvalue = 1\n", "config.toml:2: invalid table value for '--port'"},
+	}
+
+	for _, tc := range errorTestData {
+		bdd.Given(t, "a "+tc.filename+" configuration with invalid value", func(t *bdd.T) {
+			config, err := option.ParseConfig(tc.filename, []byte(tc.content))
+			require.That(t, err).IsNil()
+
+			var cmd configCommand
+			optionSet, err := option.NewOptionSet(&cmd)
+			require.That(t, err).IsNil()
+
+			t.When("calling ApplyConfig()", func(t *bdd.T) {
+				err := optionSet.ApplyConfig(config)
+
+				t.Then("the error cites the file and line", func(t *bdd.T) {
+					require.That(t, err).IsNotNil()
+					require.That(t, err.Error()).StartsWith(tc.err)
+				})
+			})
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	var errorTestData = []struct {
+		filename string
+		content  string
+		err      string
+	}{
+		{"config.yaml", "port: 1\nport: [\n", "config.yaml: yaml: line 2"},
+		{"config.yaml", "- a\n- b\n", "config.yaml:1: invalid configuration"},
+		{"config.json", "{\n  \"port\": 1,\n}", "config.json:3: "},
+		{"config.json", "\n[1, 2]", "config.json:2: invalid configuration"},
+		{"config.toml", "port = 1\nname = \n", "config.toml:2: "},
+	}
+
+	for _, tc := range errorTestData {
+		bdd.Given(t, "an invalid "+tc.filename+" configuration", func(t *bdd.T) {
+			t.When("calling ParseConfig()", func(t *bdd.T) {
+				_, err := option.ParseConfig(tc.filename, []byte(tc.content))
+
+				t.Then("the error cites the file and line", func(t *bdd.T) {
+					require.That(t, err).IsNotNil()
+					require.That(t, err.Error()).StartsWith(tc.err)
+				})
+			})
+		})
+	}
+}
+
+func TestConfigFilename(t *testing.T) {
+	bdd.Given(t, "an option set with a config-file option", func(t *bdd.T) {
+		var cmd configCommand
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+
+		t.When("the filename is given on the command-line", func(t *bdd.T) {
+			var env = map[string]string{"CONFIG": "env.yaml"}
+			var args = []string{"-vp", "80", "--config", "a.yaml", "-cb.yaml", "-t", "-c"}
+			filename, explicit := optionSet.ConfigFilename(args, env)
+
+			t.Then("the last value is returned", func(t *bdd.T) {
+				require.That(t, filename).Eq("b.yaml")
+				require.That(t, explicit).IsTrue()
+			})
+		})
+		t.When("the filename is given with --config=", func(t *bdd.T) {
+			filename, explicit := optionSet.ConfigFilename([]string{"--config=c.yaml"}, nil)
+
+			t.Then("the value is returned", func(t *bdd.T) {
+				require.That(t, filename).Eq("c.yaml")
+				require.That(t, explicit).IsTrue()
+			})
+		})
+		t.When("the filename follows grouped short flags", func(t *bdd.T) {
+			filename, _ := optionSet.ConfigFilename([]string{"-vc", "d.yaml"}, nil)
+
+			t.Then("the value is returned", func(t *bdd.T) {
+				require.That(t, filename).Eq("d.yaml")
+			})
+		})
+		t.When("the flag follows '--'", func(t *bdd.T) {
+			filename, explicit := optionSet.ConfigFilename([]string{"--", "--config=e.yaml"}, nil)
+
+			t.Then("the argument is not considered a flag", func(t *bdd.T) {
+				require.That(t, filename).Eq("app.yaml")
+				require.That(t, explicit).IsFalse()
+			})
+		})
+		t.When("the flag follows an invalid flag", func(t *bdd.T) {
+			var args = []string{"--config=f.yaml", "--bogus", "--config=g.yaml"}
+			filename, _ := optionSet.ConfigFilename(args, nil)
+
+			t.Then("scanning stops at the invalid flag, like ApplyArgs()", func(t *bdd.T) {
+				require.That(t, filename).Eq("f.yaml")
+			})
+		})
+		t.When("the filename is given in the environment", func(t *bdd.T) {
+			var env = map[string]string{"CONFIG": "env.yaml"}
+			filename, explicit := optionSet.ConfigFilename([]string{"-v"}, env)
+
+			t.Then("the environment value is returned", func(t *bdd.T) {
+				require.That(t, filename).Eq("env.yaml")
+				require.That(t, explicit).IsTrue()
+			})
+		})
+		t.When("the filename is not specified", func(t *bdd.T) {
+			filename, explicit := optionSet.ConfigFilename(nil, nil)
+
+			t.Then("the default value is returned", func(t *bdd.T) {
+				require.That(t, filename).Eq("app.yaml")
+				require.That(t, explicit).IsFalse()
+			})
+		})
+	})
+
+	bdd.Given(t, "an option set with multiple config-file options", func(t *bdd.T) {
+		type command struct {
+			Config1 string `opts:"--config1, config-file"`
+			Config2 string `opts:"--config2, config-file"`
+		}
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			_, err := option.NewOptionSet(&command{})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).IsNotNil()
+			})
+		})
+	})
+}
@@ -2,14 +2,78 @@ package option
 
 import (
 	"fmt"
+	"strings"
 )
 
+// UsageError is a custom error type wrapping errors caused by an invalid usage
+// of the command, like invalid flags, missing arguments or invalid values, as
+// opposed to errors raised while running the command.
+type UsageError struct {
+	Err error
+}
+
+func (err *UsageError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *UsageError) Unwrap() error {
+	return err.Err
+}
+
+// ExitCode returns the exit code of the process for usage errors, 2.
+func (err *UsageError) ExitCode() int {
+	return 2
+}
+
 // ErrInvalidFlag is a custom error type, raised while parsing command-line
-// arguments, indicating that a specific flag was not recognized
+// arguments, indicating that a specific flag was not recognized. `Suggestions`
+// lists the known flags with a name close to the invalid one, if any.
 type ErrInvalidFlag struct {
-	Flag string
+	Flag        string
+	Suggestions []string
 }
 
 func (err *ErrInvalidFlag) Error() string {
-	return fmt.Sprintf("invalid flag '%v'", err.Flag)
+	return fmt.Sprintf("invalid flag '%v'%v", err.Flag, FormatSuggestions(err.Suggestions))
+}
+
+// ErrAmbiguousFlag is a custom error type, raised while parsing command-line
+// arguments when abbreviations are allowed, indicating that an abbreviated flag
+// matches multiple flags, listed in `Candidates`.
+type ErrAmbiguousFlag struct {
+	Flag       string
+	Candidates []string
+}
+
+func (err *ErrAmbiguousFlag) Error() string {
+	return fmt.Sprintf("ambiguous flag '%v', could be one of '%v'",
+		err.Flag, strings.Join(err.Candidates, "', '"))
+}
+
+// FormatSuggestions returns a message suffix listing the suggested
+// corrections, or an empty string if there is none.
+func FormatSuggestions(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(", did you mean '%v'?", suggestions[0])
+	}
+	return fmt.Sprintf(", did you mean one of '%v'?", strings.Join(suggestions, "', '"))
+}
+
+// ErrValidation is a custom error type, raised after all the sources have been
+// applied, listing all the values that violate the constraints defined on the
+// options.
+type ErrValidation struct {
+	Errors []error
+}
+
+func (err *ErrValidation) Error() string {
+	var msgs []string
+	for _, e := range err.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
 }
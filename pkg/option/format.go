@@ -2,18 +2,23 @@ package option
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // FormatOptionDescription takes a list of option descriptions and returns a
 // formatted string with all the options and descriptions laid out within the
-// given width, aligning descriptions and line wraps.
+// given width, aligning descriptions and line wraps. Widths are measured in
+// terminal columns, accounting for wide and zero-width characters; ANSI escape
+// sequences are ignored for alignment.
 func FormatOptionDescription(prefix string, width int, options []Description) string {
 	var s strings.Builder
 
 	var w = 0
 	for _, opt := range options {
-		if ww := len(opt.Option); ww > w {
+		if ww := displayWidth(opt.Option); ww > w {
 			w = ww
 		}
 	}
@@ -21,10 +26,11 @@ func FormatOptionDescription(prefix string, width int, options []Description) st
 	var rw = width - len(padding)
 
 	for _, opt := range options {
+		var pad = strings.Repeat(" ", w-displayWidth(opt.Option))
 		if opt.Description == "" {
-			fmt.Fprintf(&s, "%v%*v\n", prefix, -w, opt.Option)
+			fmt.Fprintf(&s, "%v%v%v\n", prefix, opt.Option, pad)
 		} else {
-			fmt.Fprintf(&s, "%v%*v : ", prefix, -w, opt.Option)
+			fmt.Fprintf(&s, "%v%v%v : ", prefix, opt.Option, pad)
 			for i, l := range lineWrap(opt.Description, rw) {
 				if i != 0 {
 					fmt.Fprint(&s, padding)
@@ -36,11 +42,176 @@ func FormatOptionDescription(prefix string, width int, options []Description) st
 	return s.String()
 }
 
+// displayWidth returns the number of columns used to display `s` on a
+// terminal, ignoring ANSI escape sequences. East Asian wide characters and
+// emoji use two columns, combining marks and other zero-width characters none,
+// and a character joined to the previous one by a zero-width joiner is not
+// counted.
+func displayWidth(s string) (w int) {
+	var joined = false
+	for i := 0; i < len(s); {
+		if n := escapeSequenceLength(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+		if !joined {
+			w += runeWidth(r)
+		}
+		joined = r == zeroWidthJoiner
+	}
+	return
+}
+
+const zeroWidthJoiner = '\u200d'
+
+// runeWidth returns the number of columns used to display `r` on a terminal.
+func runeWidth(r rune) int {
+	if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+		return 0
+	}
+	if r < 0x300 {
+		return 1
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) ||
+		(r >= 0x1160 && r <= 0x11ff) || // Hangul medial vowels and final consonants
+		(r >= 0x1f3fb && r <= 0x1f3ff) { // Emoji skin tone modifiers
+		return 0
+	}
+	var i = sort.Search(len(wideRanges), func(i int) bool {
+		return wideRanges[i][1] >= r
+	})
+	if i < len(wideRanges) && wideRanges[i][0] <= r {
+		return 2
+	}
+	return 1
+}
+
+// wideRanges lists the sorted ranges of East Asian wide and fullwidth
+// characters, including emoji presented as wide characters.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},   // Hangul Jamo initial consonants
+	{0x231a, 0x231b},   // Watch, hourglass
+	{0x2329, 0x232a},   // Angle brackets
+	{0x23e9, 0x23ec},   // Media control symbols
+	{0x23f0, 0x23f0},   // Alarm clock
+	{0x23f3, 0x23f3},   // Hourglass with flowing sand
+	{0x25fd, 0x25fe},   // Medium small squares
+	{0x2614, 0x2615},   // Umbrella with rain drops, hot beverage
+	{0x2648, 0x2653},   // Zodiac signs
+	{0x267f, 0x267f},   // Wheelchair symbol
+	{0x2693, 0x2693},   // Anchor
+	{0x26a1, 0x26a1},   // High voltage
+	{0x26aa, 0x26ab},   // Medium circles
+	{0x26bd, 0x26be},   // Soccer ball, baseball
+	{0x26c4, 0x26c5},   // Snowman, sun behind cloud
+	{0x26ce, 0x26ce},   // Ophiuchus
+	{0x26d4, 0x26d4},   // No entry
+	{0x26ea, 0x26ea},   // Church
+	{0x26f2, 0x26f3},   // Fountain, flag in hole
+	{0x26f5, 0x26f5},   // Sailboat
+	{0x26fa, 0x26fa},   // Tent
+	{0x26fd, 0x26fd},   // Fuel pump
+	{0x2705, 0x2705},   // Check mark button
+	{0x270a, 0x270b},   // Raised fist and hand
+	{0x2728, 0x2728},   // Sparkles
+	{0x274c, 0x274c},   // Cross mark
+	{0x274e, 0x274e},   // Cross mark button
+	{0x2753, 0x2755},   // Question and exclamation marks
+	{0x2757, 0x2757},   // Heavy exclamation mark
+	{0x2795, 0x2797},   // Heavy plus, minus and division signs
+	{0x27b0, 0x27b0},   // Curly loop
+	{0x27bf, 0x27bf},   // Double curly loop
+	{0x2b1b, 0x2b1c},   // Large squares
+	{0x2b50, 0x2b50},   // Star
+	{0x2b55, 0x2b55},   // Heavy large circle
+	{0x2e80, 0x303e},   // CJK radicals, Kangxi radicals, CJK symbols and punctuation
+	{0x3041, 0x33ff},   // Hiragana, Katakana, Bopomofo, Hangul compatibility Jamo, ...
+	{0x3400, 0x4dbf},   // CJK unified ideographs extension A
+	{0x4e00, 0x9fff},   // CJK unified ideographs
+	{0xa000, 0xa4cf},   // Yi syllables and radicals
+	{0xa960, 0xa97f},   // Hangul Jamo extended A
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe10, 0xfe19},   // Vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small form variants
+	{0xff00, 0xff60},   // Fullwidth forms
+	{0xffe0, 0xffe6},   // Fullwidth signs
+	{0x16fe0, 0x16fe4}, // Ideographic symbols and punctuation
+	{0x17000, 0x18cff}, // Tangut
+	{0x1b000, 0x1b16f}, // Kana supplement and extensions
+	{0x1f004, 0x1f004}, // Mahjong tile red dragon
+	{0x1f0cf, 0x1f0cf}, // Playing card black joker
+	{0x1f18e, 0x1f18e}, // Negative squared AB
+	{0x1f191, 0x1f19a}, // Squared CL to VS
+	{0x1f200, 0x1f251}, // Enclosed ideographic supplement
+	{0x1f300, 0x1f64f}, // Miscellaneous symbols and pictographs, emoticons
+	{0x1f680, 0x1f6ff}, // Transport and map symbols
+	{0x1f7e0, 0x1f7eb}, // Large colored circles and squares
+	{0x1f90c, 0x1f9ff}, // Supplemental symbols and pictographs
+	{0x1fa70, 0x1faff}, // Symbols and pictographs extended A
+	{0x20000, 0x2fffd}, // CJK unified ideographs extensions B to F
+	{0x30000, 0x3fffd}, // CJK unified ideographs extension G
+}
+
+// truncate returns `s` shortened to fit within `w` columns, cutting on a rune
+// boundary and ending with an ellipsis if needed. If the cut leaves a style
+// escape sequence open, a reset sequence is appended after the ellipsis.
+func truncate(s string, w int) string {
+	if displayWidth(s) <= w {
+		return s
+	}
+	var ellipsis = "..."
+	if w < len(ellipsis) {
+		ellipsis = ellipsis[:w]
+	}
+	var cw = 0
+	var end = 0
+	var styled = false
+	for i := 0; i < len(s); {
+		n := escapeSequenceLength(s[i:])
+		if n == 0 {
+			var r rune
+			r, n = utf8.DecodeRuneInString(s[i:])
+			if cw+runeWidth(r) > w-len(ellipsis) {
+				break
+			}
+			cw += runeWidth(r)
+		} else if seq := s[i : i+n]; strings.HasSuffix(seq, "m") {
+			styled = seq != "\x1b[0m" && seq != "\x1b[m"
+		}
+		i += n
+		end = i
+	}
+	if styled {
+		return s[:end] + ellipsis + "\x1b[0m"
+	}
+	return s[:end] + ellipsis
+}
+
+// escapeSequenceLength returns the length of the ANSI CSI escape sequence at
+// the start of `s`, like `\x1b[1;31m`, or 0 if there is none.
+func escapeSequenceLength(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// lineWrap splits `str` into lines that fit within `w` columns, breaking at
+// white spaces and between wide characters, and preserving explicit line
+// breaks. Words wider than `w` are placed alone on their own line.
 func lineWrap(str string, w int) (lines []string) {
 	b, ws, we := nextWordRange(str, 0)
-	ls, le := ws, we
+	ls, le := ws, ws
 	for ws != we {
-		if we-ls > w || b {
+		if (b || displayWidth(str[ls:we]) > w) && le > ls {
 			lines = append(lines, str[ls:le])
 			ls = ws
 		}
@@ -68,22 +239,52 @@ func nextWordRange(s string, ii int) (b bool, i, j int) {
 		}
 		i++
 	}
+	// Words end at the next white space, and wide characters, which can be
+	// broken between in CJK text, are considered words of their own, along
+	// with the zero-width characters that follow them.
 	j = i
+	var joined = false
 	for j < l && !asciiSpace[s[j]] {
-		j++
+		if n := escapeSequenceLength(s[j:]); n > 0 {
+			j += n
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[j:])
+		var rw = runeWidth(r)
+		if rw == 2 && !joined && j > i {
+			break
+		}
+		j += n
+		joined = r == zeroWidthJoiner
+		if rw == 2 {
+			for j < l && !joined {
+				r, n := utf8.DecodeRuneInString(s[j:])
+				if r == zeroWidthJoiner {
+					joined = true
+				} else if asciiSpace[s[j]] || escapeSequenceLength(s[j:]) > 0 ||
+					runeWidth(r) != 0 {
+					break
+				}
+				j += n
+			}
+			if !joined {
+				break
+			}
+		}
 	}
 	return
 }
 
 // FormatCompletion takes a list of completion suggestions with description, and
 // returns a formatted string laid out within the given width. Long descriptions
-// are truncated to a single line with ellipsis if needed.
+// are truncated to a single line with ellipsis if needed, on a character
+// boundary.
 func FormatCompletion(width int, options []Description) string {
 	var s strings.Builder
 
 	var w = 0
 	for _, opt := range options {
-		if ww := len(opt.Option); ww > w {
+		if ww := displayWidth(opt.Option); ww > w {
 			w = ww
 		}
 	}
@@ -93,15 +294,11 @@ func FormatCompletion(width int, options []Description) string {
 	}
 
 	for _, opt := range options {
+		var pad = strings.Repeat(" ", w-displayWidth(opt.Option))
 		if dw == 0 || opt.Description == "" {
-			fmt.Fprintf(&s, "%*v\n", -w, opt.Option)
+			fmt.Fprintf(&s, "%v%v\n", opt.Option, pad)
 		} else {
-			var d = opt.Description
-			var dl = len(opt.Description)
-			if dl > dw {
-				d = d[:dw-3] + "..."
-			}
-			fmt.Fprintf(&s, "%*v : %v\n", -w, opt.Option, d)
+			fmt.Fprintf(&s, "%v%v : %v\n", opt.Option, pad, truncate(opt.Description, dw))
 		}
 	}
 	return s.String()
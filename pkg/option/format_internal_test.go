@@ -7,6 +7,55 @@ import (
 	"github.com/maargenton/go-testpredicate/pkg/verify"
 )
 
+func TestDisplayWidth(t *testing.T) {
+	var tcs = []struct {
+		s string
+		w int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"café", 4},
+		{"cafe\u0301", 4},
+		{"日本語", 6},
+		{"ｆｕｌｌ", 8},
+		{"한국어", 6},
+		{"🎉", 2},
+		{"👍🏽", 2},
+		{"❤️", 1},
+		{"👨\u200d👩\u200d👧", 2},
+		{"\x1b[1;31merror\x1b[0m", 5},
+		{"\x1b[36m日本\x1b[0m", 4},
+	}
+
+	for _, tc := range tcs {
+		verify.That(t, displayWidth(tc.s)).Eq(tc.w)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var tcs = []struct {
+		s string
+		w int
+		r string
+	}{
+		{"hello", 5, "hello"},
+		{"hello world", 8, "hello..."},
+		{"日本語の説明", 12, "日本語の説明"},
+		{"日本語の説明", 10, "日本語..."},
+		{"日本語の説明", 8, "日本..."},
+		{"🎉🎉🎉🎉", 7, "🎉🎉..."},
+		{"e\u0301e\u0301e\u0301e\u0301e\u0301", 4, "e\u0301..."},
+		{"hello", 2, ".."},
+		{"\x1b[1mhello world\x1b[0m", 8, "\x1b[1mhello...\x1b[0m"},
+		{"\x1b[1mhello\x1b[0m world", 8, "\x1b[1mhello\x1b[0m..."},
+		{"\x1b[1mhello\x1b[0m", 8, "\x1b[1mhello\x1b[0m"},
+	}
+
+	for _, tc := range tcs {
+		verify.That(t, truncate(tc.s, tc.w)).Eq(tc.r)
+	}
+}
+
 func TestLineWrap(t *testing.T) {
 	t.Run("Given a piece of text wider than allowed", func(t *testing.T) {
 		text := "generate a bash script used to setup completion for this command; " +
@@ -54,4 +103,65 @@ func TestLineWrap(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("Given a piece of text in Japanese", func(t *testing.T) {
+		text := "日本語の説明文は単語の間に空白がありません"
+
+		t.Run("when calling lineWrap()", func(t *testing.T) {
+			lines := lineWrap(text, 16)
+
+			t.Run("then lines are broken between wide characters", func(t *testing.T) {
+				verify.That(t, lines).Eq([]string{
+					"日本語の説明文は",
+					"単語の間に空白が",
+					"ありません",
+				})
+			})
+		})
+	})
+
+	t.Run("Given a piece of text mixing latin and CJK", func(t *testing.T) {
+		text := "use 日本語 text with accents like café"
+
+		t.Run("when calling lineWrap()", func(t *testing.T) {
+			lines := lineWrap(text, 12)
+
+			t.Run("then lines fit within the display width", func(t *testing.T) {
+				verify.That(t, lines).Eq([]string{
+					"use 日本語",
+					"text with",
+					"accents like",
+					"café",
+				})
+			})
+		})
+	})
+
+	t.Run("Given a piece of text with emoji sequences", func(t *testing.T) {
+		text := "👨\u200d👩\u200d👧👍🏽🎉"
+
+		t.Run("when calling lineWrap()", func(t *testing.T) {
+			lines := lineWrap(text, 2)
+
+			t.Run("then emoji sequences are not broken", func(t *testing.T) {
+				verify.That(t, lines).Eq([]string{
+					"👨\u200d👩\u200d👧",
+					"👍🏽",
+					"🎉",
+				})
+			})
+		})
+	})
+
+	t.Run("Given a word wider than allowed", func(t *testing.T) {
+		text := "extraordinarily long"
+
+		t.Run("when calling lineWrap()", func(t *testing.T) {
+			lines := lineWrap(text, 5)
+
+			t.Run("then no empty line is produced", func(t *testing.T) {
+				verify.That(t, lines).Eq([]string{"extraordinarily", "long"})
+			})
+		})
+	})
 }
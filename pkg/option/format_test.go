@@ -3,6 +3,7 @@ package option_test
 import (
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/maargenton/go-testpredicate/pkg/require"
 	"github.com/maargenton/go-testpredicate/pkg/subexpr"
@@ -56,6 +57,57 @@ func TestFormatOptionDescription(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("Given a list of argument usage with ANSI escape sequences", func(t *testing.T) {
+		var usage = []option.Description{
+			{"\x1b[36m-p, --port\x1b[0m \x1b[33m<value>\x1b[0m", "port"},
+			{"\x1b[36m-v, --verbose\x1b[0m", "verbose"},
+		}
+
+		t.Run("when calling FormatOptionDescription()", func(t *testing.T) {
+			var lines = lines(option.FormatOptionDescription("__", 80, usage))
+
+			t.Run("then escape sequences are ignored for alignment", func(t *testing.T) {
+				require.That(t, lines[0]).Eq("__\x1b[36m-p, --port\x1b[0m \x1b[33m<value>\x1b[0m : port")
+				require.That(t, lines[1]).Eq("__\x1b[36m-v, --verbose\x1b[0m      : verbose")
+			})
+		})
+	})
+
+	t.Run("Given a list of argument usage with wide characters", func(t *testing.T) {
+		var usage = []option.Description{
+			{"--名前 <値>", "名前を設定する"},
+			{"--café", "accentué"},
+			{"--emoji 🎉", "fête"},
+		}
+
+		t.Run("when calling FormatOptionDescription()", func(t *testing.T) {
+			var lines = lines(option.FormatOptionDescription("__", 80, usage))
+
+			t.Run("then descriptions are aligned on display width", func(t *testing.T) {
+				require.That(t, lines[0]).Eq("__--名前 <値> : 名前を設定する")
+				require.That(t, lines[1]).Eq("__--café      : accentué")
+				require.That(t, lines[2]).Eq("__--emoji 🎉  : fête")
+			})
+		})
+	})
+
+	t.Run("Given a list of argument usage with long CJK description", func(t *testing.T) {
+		var usage = []option.Description{
+			{"-n, --name", "このオプションはコマンドの名前を設定します。名前は必須ではありません。"},
+		}
+
+		t.Run("when calling FormatOptionDescription()", func(t *testing.T) {
+			var lines = lines(option.FormatOptionDescription("  ", 40, usage))
+
+			t.Run("then description is wrapped between wide characters", func(t *testing.T) {
+				require.That(t, lines).Length().Eq(3)
+				require.That(t, lines[0]).Eq("  -n, --name : このオプションはコマンド")
+				require.That(t, lines[1]).Eq("               の名前を設定します。名前")
+				require.That(t, lines[2]).Eq("               は必須ではありません。")
+			})
+		})
+	})
 }
 
 func TestFormatCompletion(t *testing.T) {
@@ -86,4 +138,35 @@ func TestFormatCompletion(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("Given a list of completion suggestions with wide characters", func(t *testing.T) {
+		var suggestions = []option.Description{
+			{"日本", "日本語の説明はとても長いので切り詰められます"},
+			{"emoji", "🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉"},
+		}
+
+		t.Run("when calling FormatCompletion() with narrow width", func(t *testing.T) {
+			l := lines(option.FormatCompletion(30, suggestions))
+
+			t.Run("then descriptions are truncated on character boundaries", func(t *testing.T) {
+				require.That(t, l[0]).Eq("日本  : 日本語の説明はとて...")
+				require.That(t, l[1]).Eq("emoji : 🎉🎉🎉🎉🎉🎉🎉🎉🎉...")
+				require.That(t, utf8.ValidString(l[0])).IsTrue()
+				require.That(t, utf8.ValidString(l[1])).IsTrue()
+			})
+		})
+	})
+	t.Run("Given a completion suggestion with a styled description", func(t *testing.T) {
+		var suggestions = []option.Description{
+			{"style", "\x1b[1mbold description that is too long to fit\x1b[0m"},
+		}
+
+		t.Run("when calling FormatCompletion() with narrow width", func(t *testing.T) {
+			l := lines(option.FormatCompletion(30, suggestions))
+
+			t.Run("then the style is reset after the ellipsis", func(t *testing.T) {
+				require.That(t, l[0]).Eq("style : \x1b[1mbold description th...\x1b[0m")
+			})
+		})
+	})
 }
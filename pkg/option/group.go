@@ -0,0 +1,187 @@
+package option
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GroupKind describes the rule applied to the options of a group.
+type GroupKind int
+
+// Contant values for GroupKind
+const (
+	ExclusiveGroup GroupKind = iota
+	TogetherGroup
+	OneRequiredGroup
+)
+
+// String returns the name of the tag declaring groups of that kind.
+func (k GroupKind) String() string {
+	switch k {
+	case ExclusiveGroup:
+		return "exclusive"
+	case TogetherGroup:
+		return "together"
+	case OneRequiredGroup:
+		return "one-required"
+	}
+	return "GroupKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Group is a named set of options subject to a common rule, declared with the
+// `exclusive:`, `together:` or `one-required:` tags. Only options explicitly
+// set from a configuration file, the environment or the command-line are
+// considered when checking the rule.
+type Group struct {
+	Kind    GroupKind
+	Name    string
+	Options []*T
+}
+
+// Description returns a short description of the rule applied to the group,
+// used to display usage.
+func (g *Group) Description() string {
+	switch g.Kind {
+	case ExclusiveGroup:
+		return "mutually exclusive"
+	case TogetherGroup:
+		return "must be used together"
+	case OneRequiredGroup:
+		return "at least one required"
+	}
+	return ""
+}
+
+// check returns an error if the current state of the options violates the rule
+// of the group.
+func (g *Group) check() error {
+	var set, unset []*T
+	for _, opt := range g.Options {
+		if opt.isExplicit() {
+			set = append(set, opt)
+		} else {
+			unset = append(unset, opt)
+		}
+	}
+
+	switch g.Kind {
+	case ExclusiveGroup:
+		if len(set) > 1 {
+			return fmt.Errorf("options %v are mutually exclusive", quoteNames(set))
+		}
+	case TogetherGroup:
+		if len(set) > 0 && len(unset) > 0 {
+			return fmt.Errorf("missing %v, required with %v", quoteNames(unset), quoteNames(set))
+		}
+	case OneRequiredGroup:
+		if len(set) == 0 {
+			return fmt.Errorf("one of %v is required", quoteNames(g.Options))
+		}
+	}
+	return nil
+}
+
+// isExplicit returns true if the value of the option was set from a source
+// other than its default value.
+func (opt *T) isExplicit() bool {
+	return opt.source.Kind != Unset && opt.source.Kind != DefaultSource
+}
+
+func quoteNames(options []*T) string {
+	var names []string
+	for _, opt := range options {
+		names = append(names, "'"+opt.Name()+"'")
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkGroups returns the list of all the group rules violated by the current
+// state of the options.
+func (opts *Set) checkGroups() (errs []error) {
+	for _, g := range opts.Groups {
+		if err := g.check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// excludedOptions returns the set of options excluded by the use of any of the
+// options in `used`, through an exclusive group.
+func (opts *Set) excludedOptions(used map[*T]struct{}) map[*T]struct{} {
+	var excluded = make(map[*T]struct{})
+	for _, g := range opts.Groups {
+		if g.Kind != ExclusiveGroup {
+			continue
+		}
+		for _, opt := range g.Options {
+			if _, ok := used[opt]; ok {
+				for _, o := range g.Options {
+					if o != opt {
+						excluded[o] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+	return excluded
+}
+
+// parseGroups collects the groups declared by the options of the set, in order
+// of first appearance.
+func (opts *Set) parseGroups() error {
+	var kinds = []GroupKind{ExclusiveGroup, TogetherGroup, OneRequiredGroup}
+	var groups = make(map[GroupKind]map[string]*Group)
+	for _, kind := range kinds {
+		groups[kind] = make(map[string]*Group)
+	}
+
+	for _, opt := range opts.Options {
+		for _, kind := range kinds {
+			var name = opt.groupName(kind)
+			if name == "" {
+				continue
+			}
+			var g = groups[kind][name]
+			if g == nil {
+				g = &Group{Kind: kind, Name: name}
+				groups[kind][name] = g
+				opts.Groups = append(opts.Groups, g)
+			}
+			g.Options = append(g.Options, opt)
+		}
+	}
+
+	for _, g := range opts.Groups {
+		if g.Kind != OneRequiredGroup && len(g.Options) < 2 {
+			return fmt.Errorf(
+				"%v group '%v' must contain at least two options", g.Kind, g.Name)
+		}
+	}
+	for _, opt := range opts.all() {
+		if opt.Position == 0 && !opt.Args {
+			continue
+		}
+		for _, kind := range kinds {
+			if opt.groupName(kind) != "" {
+				return fmt.Errorf(
+					"%v group not supported on positional argument '%v'",
+					kind, opt.Name())
+			}
+		}
+	}
+	return nil
+}
+
+func (opt *T) groupName(kind GroupKind) string {
+	switch kind {
+	case ExclusiveGroup:
+		return opt.Exclusive
+	case TogetherGroup:
+		return opt.Together
+	case OneRequiredGroup:
+		return opt.OneRequired
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package option_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maargenton/go-testpredicate/pkg/bdd"
+	"github.com/maargenton/go-testpredicate/pkg/require"
+
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+type groupCommand struct {
+	File  string `opts:"--file, exclusive:input, one-required:input"`
+	URL   string `opts:"--url, exclusive:input, one-required:input, env:URL"`
+	Stdin bool   `opts:"--stdin, exclusive:input, one-required:input"`
+	Cert  string `opts:"--cert, together:tls"`
+	Key   string `opts:"--key, together:tls, default:key.pem"`
+}
+
+func TestGroups(t *testing.T) {
+	bdd.Given(t, "an option set with option groups", func(t *bdd.T) {
+		var cmd groupCommand
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+		require.That(t, optionSet.ApplyDefaults()).IsNil()
+
+		t.When("inspecting the groups", func(t *bdd.T) {
+			var groups = optionSet.Groups
+
+			t.Then("groups are listed in order of first appearance", func(t *bdd.T) {
+				require.That(t, groups).Length().Eq(3)
+				require.That(t, groups[0]).Field("Kind").Eq(option.ExclusiveGroup)
+				require.That(t, groups[0]).Field("Options").Length().Eq(3)
+				require.That(t, groups[1]).Field("Kind").Eq(option.OneRequiredGroup)
+				require.That(t, groups[2]).Field("Kind").Eq(option.TogetherGroup)
+				require.That(t, groups[2]).Field("Name").Eq("tls")
+			})
+		})
+
+		t.When("calling Validate() with valid arguments", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs([]string{"--file", "a.txt", "--cert", "c.pem", "--key", "k.pem"})).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("no error is returned", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+			})
+		})
+
+		t.When("calling Validate() with mutually exclusive options", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyEnv(map[string]string{"URL": "http://x"})).IsNil()
+			require.That(t, optionSet.ApplyArgs([]string{"--stdin"})).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("an error naming the options is returned", func(t *bdd.T) {
+				var verr *option.ErrValidation
+				require.That(t, errors.As(err, &verr)).IsTrue()
+				require.That(t, err).ToString().Eq("options '--url', '--stdin' are mutually exclusive")
+			})
+		})
+
+		t.When("calling Validate() with a partial together group", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs([]string{"--stdin", "--cert", "c.pem"})).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("default values do not satisfy the group", func(t *bdd.T) {
+				require.That(t, err).ToString().Eq("missing '--key', required with '--cert'")
+			})
+		})
+
+		t.When("calling Validate() without any of a one-required group", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs(nil)).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("an error listing the options is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Eq("one of '--file', '--url', '--stdin' is required")
+			})
+		})
+
+		t.When("calling GetCompletion() after an exclusive option", func(t *bdd.T) {
+			completion := optionSet.GetCompletion([]string{"--file", "a.txt"}, "")
+
+			t.Then("the other options of the group are not suggested", func(t *bdd.T) {
+				require.That(t, completion.Options).Length().Eq(2)
+				require.That(t, completion.Options).IsEqualSet([]string{"--cert", "--key"})
+			})
+		})
+	})
+}
+
+func TestGroups_Invalid(t *testing.T) {
+	var tcs = []struct {
+		name string
+		cmd  interface{}
+		err  string
+	}{
+		{"a single-option exclusive group", &struct {
+			A bool `opts:"-a, exclusive:g"`
+		}{}, "exclusive group 'g' must contain at least two options"},
+		{"a group on a positional argument", &struct {
+			A bool   `opts:"-a, together:g"`
+			B bool   `opts:"-b, together:g"`
+			C string `opts:"arg:1, together:g"`
+		}{}, "together group not supported on positional argument '<arg1>'"},
+	}
+
+	for _, tc := range tcs {
+		bdd.Given(t, "a struct with "+tc.name, func(t *bdd.T) {
+			t.When("calling NewOptionSet()", func(t *bdd.T) {
+				_, err := option.NewOptionSet(tc.cmd)
+
+				t.Then("an error is returned", func(t *bdd.T) {
+					require.That(t, err).ToString().Contains(tc.err)
+				})
+			})
+		})
+	}
+}
@@ -3,6 +3,7 @@ package option
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -12,7 +13,9 @@ import (
 // Type describes the type of option encoded in an `option.T` as either a
 // `Value` that expect a value, a `Bool` that does not take a value, a
 // `Ptr` which can be optional, a `Slice` that accept multiple values,
-// or a `Special` that precludes the use of any other option.
+// a `Special` that precludes the use of any other option, a `Count` that
+// does not take a value and counts occurrences, or a `Map` that accept multiple
+// key-value pairs.
 type Type int
 
 // Contant values for Type
@@ -22,8 +25,31 @@ const (
 	Ptr
 	Slice
 	Special
+	Count
+	Map
 )
 
+// String returns a lower-case name for the option type.
+func (t Type) String() string {
+	switch t {
+	case Value:
+		return "value"
+	case Bool:
+		return "bool"
+	case Ptr:
+		return "ptr"
+	case Slice:
+		return "slice"
+	case Special:
+		return "special"
+	case Count:
+		return "count"
+	case Map:
+		return "map"
+	}
+	return "Type(" + strconv.Itoa(int(t)) + ")"
+}
+
 // T represents a single option with a reference back to the OptionSet it
 // belongs to.
 type T struct {
@@ -32,22 +58,48 @@ type T struct {
 	Default     string
 	Env         string
 	Sep         string // optional separator
+	KVSep       string // optional key-value separator for maps, defaults to '='
 	KeepSpaces  bool
 	KeepEmpty   bool
 	Description string
 	ValueName   string // optional name for the value
 	Position    int    // set to non-zero for fields capturing positional arguments
 	Args        bool   // set to true for the field capturing remaining arguments
+	MinCount    int    // minimum number of values for slice positional arguments
+	MaxCount    int    // maximum number of values for slice positional arguments, if non-zero
+	ConfigKey   string // optional key in configuration files, defaults to long name
+	ConfigFile  bool   // set to true for the field naming the configuration file
+	Required    bool   // set to true for options that must be set from any source
+	Min         string // optional minimum value
+	Max         string // optional maximum value
+	Choices     []string
+	Pattern     string // optional regular expression matched by values
+	Exclusive   string // optional name of a group of mutually exclusive options
+	Together    string // optional name of a group of options used together
+	OneRequired string // optional name of a group of options, one of which is required
+	Negatable   bool   // set to true for bool options accepting a `--no-` prefix
+
+	Section       string // optional title of the usage section listing the option
+	OptionalValue bool   // set to true for flags taking a value only with `=`
+	ImplicitValue string // value used for optional-value flags specified bare
 
 	FieldName  string
 	Index      []int
 	FieldType  reflect.Type
 	ValueType  reflect.Type
+	KeyType    reflect.Type // set for map options only
 	Type       Type
 	Optional   bool
 	SpecialErr error
-
-	opts *Set
+	Hidden     bool // set to true to omit the option from usage and completion
+	Deferred   bool // set to true for special flags that do not interrupt parsing
+
+	opts    *Set
+	source  Source
+	min     reflect.Value
+	max     reflect.Value
+	choices []reflect.Value
+	pattern *regexp.Regexp
 }
 
 // Description captures both an option and its description. This is used
@@ -67,10 +119,14 @@ func (opt *T) Name() string {
 		return "<args>..."
 	}
 	if opt.Position != 0 {
+		var suffix = ""
+		if opt.Type == Slice {
+			suffix = "..."
+		}
 		if opt.ValueName != "" {
-			return fmt.Sprintf("<%v>", opt.ValueName)
+			return fmt.Sprintf("<%v>%v", opt.ValueName, suffix)
 		}
-		return fmt.Sprintf("<arg%d>", opt.Position)
+		return fmt.Sprintf("<arg%d>%v", opt.Position, suffix)
 	}
 	if opt.Long == "" {
 		return fmt.Sprintf("-%v", opt.Short)
@@ -81,20 +137,37 @@ func (opt *T) Name() string {
 // GetUsage returns a formated representation of the option used to display
 // usage and its description.
 func (opt *T) GetUsage() (usage Description) {
+	return opt.GetStyledUsage(nil, nil)
+}
+
+// GetStyledUsage returns the same usage as `GetUsage()`, with the flag names
+// and the value placeholders passed through the `flag` and `value` functions
+// respectively, typically to add ANSI escape sequences. A nil function leaves
+// the corresponding text unchanged.
+func (opt *T) GetStyledUsage(flag, value func(string) string) (usage Description) {
+	if flag == nil {
+		flag = func(s string) string { return s }
+	}
+	if value == nil {
+		value = func(s string) string { return s }
+	}
 
 	var u strings.Builder
 	if opt.Position != 0 || opt.Args {
-		fmt.Fprintf(&u, "%v", opt.Name())
+		fmt.Fprintf(&u, "%v", value(opt.Name()))
 	} else {
-		if opt.Short != "" && opt.Long != "" {
-			fmt.Fprintf(&u, "-%v, --%v", opt.Short, opt.Long)
-		} else if opt.Short != "" {
-			fmt.Fprintf(&u, "-%v", opt.Short)
-		} else if opt.Long != "" {
-			fmt.Fprintf(&u, "    --%v", opt.Long)
+		if opt.Short == "" && opt.Long != "" {
+			u.WriteString("    ")
 		}
+		u.WriteString(flag(opt.FlagsUsage()))
 
-		if v := opt.getValueDescription(); v != "" {
+		if v := value(opt.ValueUsage()); v != "" && opt.OptionalValue {
+			if opt.Long != "" {
+				fmt.Fprintf(&u, "[=%v]", v)
+			} else {
+				fmt.Fprintf(&u, "[%v]", v)
+			}
+		} else if v != "" {
 			if u.Len() > 0 {
 				u.WriteRune(' ')
 			}
@@ -108,16 +181,66 @@ func (opt *T) GetUsage() (usage Description) {
 	}
 }
 
-func (opt *T) getValueDescription() string {
-	if opt.Type == Bool || opt.Type == Special {
+// FlagsUsage returns the short and long flags of the option, as displayed in
+// the usage, e.g. `-c, --[no-]color`.
+func (opt *T) FlagsUsage() string {
+	var long = opt.Long
+	if opt.Negatable {
+		long = "[no-]" + long
+	}
+	if opt.Short != "" && opt.Long != "" {
+		return fmt.Sprintf("-%v, --%v", opt.Short, long)
+	} else if opt.Short != "" {
+		return fmt.Sprintf("-%v", opt.Short)
+	} else if opt.Long != "" {
+		return fmt.Sprintf("--%v", long)
+	}
+	return ""
+}
+
+// ValueUsage returns the name of the value expected by the option, as
+// displayed in the usage, or an empty string for options taking no value.
+func (opt *T) ValueUsage() string {
+	if opt.Type == Bool || opt.Type == Special || opt.Type == Count {
 		return ""
 	}
 	if opt.ValueName != "" {
 		return fmt.Sprintf("<%v>", opt.ValueName)
 	}
+	if opt.Type == Map {
+		return fmt.Sprintf("<key>%v<value>", opt.kvSep())
+	}
 	return "<value>"
 }
 
+// ConstraintsUsage returns the constraints defined on the option, as displayed
+// in the usage, e.g. `required, min: 1`.
+func (opt *T) ConstraintsUsage() string {
+	var constraints []string
+	if opt.Required {
+		constraints = append(constraints, "required")
+	}
+	if opt.Min != "" {
+		constraints = append(constraints, "min: "+opt.Min)
+	}
+	if opt.Max != "" {
+		constraints = append(constraints, "max: "+opt.Max)
+	}
+	if len(opt.Choices) != 0 {
+		constraints = append(constraints, "choices: "+strings.Join(opt.Choices, "|"))
+	}
+	if opt.Pattern != "" {
+		constraints = append(constraints, "pattern: "+opt.Pattern)
+	}
+	if opt.MinCount != 0 {
+		constraints = append(constraints, fmt.Sprintf("min count: %v", opt.MinCount))
+	}
+	if opt.MaxCount != 0 {
+		constraints = append(constraints, fmt.Sprintf("max count: %v", opt.MaxCount))
+	}
+	return strings.Join(constraints, ", ")
+}
+
 func (opt *T) getDescription() string {
 	var d strings.Builder
 	d.WriteString(opt.Description)
@@ -135,6 +258,13 @@ func (opt *T) getDescription() string {
 		fmt.Fprintf(&d, "env: %v", opt.Env)
 	}
 
+	if c := opt.ConstraintsUsage(); c != "" {
+		if d.Len() > 0 {
+			fmt.Fprintf(&d, ", ")
+		}
+		d.WriteString(c)
+	}
+
 	return d.String()
 }
 
@@ -143,23 +273,62 @@ func (opt *T) SetBool() {
 	if opt.Type != Bool {
 		panic("cannot call Option.SetBool() on non-bool fields")
 	}
+	opt.setBool(true)
+}
 
+func (opt *T) setBool(b bool) {
 	var fv = opt.opts.target.FieldByIndex(opt.Index)
 	if fv.Kind() == reflect.Ptr {
 		var v = reflect.New(fv.Type().Elem())
-		v.Elem().SetBool(true)
+		v.Elem().SetBool(b)
 		fv.Set(v)
 	} else {
-		fv.SetBool(true)
+		fv.SetBool(b)
+	}
+}
+
+// checkCount returns a usage error if `n` values are not within the range
+// accepted by a slice positional argument. A missing value is not reported if
+// `partial` is set.
+func (opt *T) checkCount(n int, partial bool) error {
+	if n < opt.MinCount && !partial {
+		if n <= 0 {
+			return &UsageError{Err: fmt.Errorf("missing argument for '%v'", opt.Name())}
+		}
+		return &UsageError{Err: fmt.Errorf(
+			"not enough arguments for '%v', at least %v expected", opt.Name(), opt.MinCount)}
+	}
+	if opt.MaxCount != 0 && n > opt.MaxCount {
+		return &UsageError{Err: fmt.Errorf(
+			"too many arguments for '%v', at most %v expected", opt.Name(), opt.MaxCount)}
+	}
+	return nil
+}
+
+// Increment is a special setter usable only on count flags to increment their
+// value by one.
+func (opt *T) Increment() {
+	if opt.Type != Count {
+		panic("cannot call Option.Increment() on non-count fields")
+	}
+
+	var fv = opt.opts.target.FieldByIndex(opt.Index)
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fv.SetInt(fv.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fv.SetUint(fv.Uint() + 1)
 	}
 }
 
 // SetValue convert the strign `s` into a value of the desired type and assigns
 // it to the struct field backing the current options. It supports value type,
-// pointer type and slice type. For pointer type and slice type, an empty value
-// reverts the field to a null pointer or an empty slice. For slice types
-// defining a delimiter, the value is split accordingly and the delimited values
-// are added to the slice.
+// pointer type, slice type and map type. For pointer type, slice type and map
+// type, an empty value reverts the field to a null pointer, an empty slice or
+// an empty map. For slice types defining a delimiter, the value is split
+// accordingly and the delimited values are added to the slice. For map types,
+// the value is split into entries with the delimiter, defaulting to ',', and
+// each entry is split into a key and a value with the key-value separator.
 func (opt *T) SetValue(s string) error {
 	var fv = opt.opts.target.FieldByIndex(opt.Index)
 	var err error
@@ -172,6 +341,8 @@ func (opt *T) SetValue(s string) error {
 		err = opt.setPtrValue(fv, s)
 	} else if opt.Type == Slice {
 		err = opt.setSliceValue(fv, s)
+	} else if opt.Type == Map {
+		err = opt.setMapValue(fv, s)
 	} else {
 		err = value.Parse(fv.Addr().Interface(), s)
 	}
@@ -221,6 +392,60 @@ func (opt *T) setSliceValue(fv reflect.Value, s string) error {
 	return nil
 }
 
+func (opt *T) setMapValue(fv reflect.Value, s string) error {
+	if len(s) == 0 {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+
+	var sep = opt.Sep
+	if sep == "" {
+		sep = ","
+	}
+	for _, entry := range splitSliceValues(s, sep) {
+		if !opt.KeepSpaces {
+			entry = strings.TrimSpace(entry)
+		}
+		if entry == "" {
+			continue
+		}
+		var i = strings.Index(entry, opt.kvSep())
+		if i < 0 {
+			return fmt.Errorf("missing '%v' in map entry '%v'", opt.kvSep(), entry)
+		}
+		if err := opt.setMapEntry(fv, entry[:i], entry[i+len(opt.kvSep()):]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (opt *T) setMapEntry(fv reflect.Value, ks, vs string) error {
+	if !opt.KeepSpaces {
+		ks, vs = strings.TrimSpace(ks), strings.TrimSpace(vs)
+	}
+	var k = reflect.New(opt.KeyType)
+	if err := value.Parse(k.Interface(), ks); err != nil {
+		return err
+	}
+	var v = reflect.New(opt.ValueType)
+	if err := value.Parse(v.Interface(), vs); err != nil {
+		return err
+	}
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(fv.Type()))
+	}
+	fv.SetMapIndex(k.Elem(), v.Elem())
+	return nil
+}
+
+func (opt *T) kvSep() string {
+	if opt.KVSep != "" {
+		return opt.KVSep
+	}
+	return "="
+}
+
 func splitSliceValues(s string, delim string) (r []string) {
 	var escape = false
 	var b strings.Builder
@@ -270,18 +495,59 @@ func (opt *T) parseOptsTag(tag string) error {
 				return fmt.Errorf("invalid index '0' for arg: tag")
 			}
 			opt.Position = int(n)
+		} else if k == "min-count" || k == "max-count" {
+			n, err := strconv.ParseInt(v, 0, 0)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid count '%v' for %v: tag", v, k)
+			}
+			if k == "min-count" {
+				opt.MinCount = int(n)
+			} else {
+				opt.MaxCount = int(n)
+			}
 		} else if k == "default" {
 			opt.Default = v
 		} else if k == "env" {
 			opt.Env = v
 		} else if k == "sep" {
 			opt.Sep = v
+		} else if k == "kv-sep" {
+			opt.KVSep = v
 		} else if k == "name" {
 			opt.ValueName = v
 		} else if k == "keep-spaces" {
 			opt.KeepSpaces = true
 		} else if k == "keep-empty" {
 			opt.KeepEmpty = true
+		} else if k == "config-key" {
+			opt.ConfigKey = v
+		} else if k == "config-file" && v == "" {
+			opt.ConfigFile = true
+		} else if k == "required" && v == "" {
+			opt.Required = true
+		} else if k == "min" {
+			opt.Min = v
+		} else if k == "max" {
+			opt.Max = v
+		} else if k == "choices" {
+			opt.Choices = strings.Split(v, "|")
+		} else if k == "pattern" {
+			opt.Pattern = v
+		} else if k == "exclusive" {
+			opt.Exclusive = v
+		} else if k == "together" {
+			opt.Together = v
+		} else if k == "one-required" {
+			opt.OneRequired = v
+		} else if k == "negatable" && v == "" {
+			opt.Negatable = true
+		} else if k == "count" && v == "" {
+			opt.Type = Count
+		} else if k == "section" {
+			opt.Section = v
+		} else if k == "optional-value" {
+			opt.OptionalValue = true
+			opt.ImplicitValue = v
 		} else {
 			return fmt.Errorf("invalid tag in opts: '%v'", k)
 		}
@@ -180,6 +180,61 @@ func TestOptionDescriptionUsage(t *testing.T) {
 			},
 			usage: "-d, --debug",
 		},
+		{
+			name: "a negatable boolean Option",
+			opt: option.T{
+				Short:     "c",
+				Long:      "color",
+				Type:      option.Bool,
+				Negatable: true,
+			},
+			usage: "-c, --[no-]color",
+		},
+		{
+			name: "a count Option",
+			opt: option.T{
+				Short: "v",
+				Long:  "verbose",
+				Type:  option.Count,
+			},
+			usage: "-v, --verbose",
+		},
+		{
+			name: "a map Option",
+			opt: option.T{
+				Short: "l",
+				Long:  "label",
+				Type:  option.Map,
+			},
+			usage: "-l, --label <key>=<value>",
+		},
+		{
+			name: "a map Option with custom key-value separator",
+			opt: option.T{
+				Long:  "limit",
+				Type:  option.Map,
+				KVSep: ":",
+			},
+			usage: "    --limit <key>:<value>",
+		},
+		{
+			name: "an Option with optional value",
+			opt: option.T{
+				Short:         "c",
+				Long:          "color",
+				ValueName:     "when",
+				OptionalValue: true,
+			},
+			usage: "-c, --color[=<when>]",
+		},
+		{
+			name: "an Option with optional value and short only",
+			opt: option.T{
+				Short:         "c",
+				OptionalValue: true,
+			},
+			usage: "-c[<value>]",
+		},
 		{
 			name: "a special Option",
 			opt: option.T{
@@ -238,6 +293,18 @@ func TestOptionDescriptionDescription(t *testing.T) {
 			},
 			desc: "default: default, env: ENV",
 		},
+		{
+			name: "an Option{} with constraints",
+			opt: option.T{
+				Description: "description",
+				Required:    true,
+				Min:         "1",
+				Max:         "10",
+				Choices:     []string{"1", "2", "10"},
+				Pattern:     "^[0-9]+$",
+			},
+			desc: "description, required, min: 1, max: 10, choices: 1|2|10, pattern: ^[0-9]+$",
+		},
 	}
 
 	for _, tc := range tcs {
@@ -17,6 +17,11 @@ type Set struct {
 	Options    []*T
 	Positional []*T
 	Args       *T
+	Groups     []*Group
+
+	// AllowAbbreviations enables the resolution of long flags from any
+	// unambiguous prefix of at least two characters of their name.
+	AllowAbbreviations bool
 }
 
 // NewOptionSet creates a new Set that reflects the field in type `t`
@@ -38,9 +43,20 @@ func NewOptionSet(v interface{}) (*Set, error) {
 }
 
 // GetOption return the option matching the specified name, which can be the
-// short or the long name of the flag, without any leading dash. Returns nil is
-// no matching flag is found.
+// short or the long name of the flag, without any leading dash. When
+// `AllowAbbreviations` is set, the name can also be an unambiguous prefix of
+// the long name. Returns nil is no matching flag is found.
 func (opts *Set) GetOption(name string) (opt *T) {
+	if opt = opts.getExactOption(name); opt != nil {
+		return opt
+	}
+	if matches := opts.abbreviationMatches(name); len(matches) == 1 {
+		return matches[0]
+	}
+	return nil
+}
+
+func (opts *Set) getExactOption(name string) (opt *T) {
 	if name != "" {
 		for _, opt := range opts.Options {
 			if opt.Short == name || opt.Long == name {
@@ -51,18 +67,46 @@ func (opts *Set) GetOption(name string) (opt *T) {
 	return nil
 }
 
+// negatedOption returns the negatable bool option named by `name` without its
+// `no-` prefix, or nil if there is none.
+func (opts *Set) negatedOption(name string) *T {
+	if !strings.HasPrefix(name, "no-") {
+		return nil
+	}
+	if opt := opts.GetOption(name[3:]); opt != nil && opt.Negatable {
+		return opt
+	}
+	return nil
+}
+
+// abbreviationMatches returns all the visible options whose long name starts
+// with `name`, if abbreviations are allowed and `name` is at least two
+// characters long.
+func (opts *Set) abbreviationMatches(name string) (matches []*T) {
+	if !opts.AllowAbbreviations || len(name) < 2 {
+		return nil
+	}
+	for _, opt := range opts.Options {
+		if !opt.Hidden && strings.HasPrefix(opt.Long, name) {
+			matches = append(matches, opt)
+		}
+	}
+	return matches
+}
+
 // AddSpecialFlag appends a special flag to the option set, that sends an
 // sentinel error when found on the command-line. Used for `--version` and
 // `--help`. The short flag is up-cased or dropped if conflicting with existing
 // flags. The whole special flag is dropped if the long flag is conflicting.
-func (opts *Set) AddSpecialFlag(short, long, desc string, err error) {
-	if opts.GetOption(long) != nil {
-		return
+// Returns the newly added flag, or nil if it was dropped.
+func (opts *Set) AddSpecialFlag(short, long, desc string, err error) *T {
+	if opts.getExactOption(long) != nil {
+		return nil
 	}
 
-	if opts.GetOption(short) != nil {
+	if opts.getExactOption(short) != nil {
 		short = strings.ToUpper(short)
-		if opts.GetOption(short) != nil {
+		if opts.getExactOption(short) != nil {
 			short = ""
 		}
 	}
@@ -78,6 +122,7 @@ func (opts *Set) AddSpecialFlag(short, long, desc string, err error) {
 	}
 
 	opts.Options = append(opts.Options, opt)
+	return opt
 }
 
 // ApplyDefaults scans through a parsed option set and applies the values
@@ -89,22 +134,25 @@ func (opts *Set) ApplyDefaults() error {
 			if err := opt.SetValue(opt.Default); err != nil {
 				return fmt.Errorf("while applying defaults, %w", err)
 			}
+			opt.source = Source{Kind: DefaultSource}
 		}
 	}
 	return nil
 }
 
 // ApplyEnv scans through a parsed option set and applies the corresponding
-// default values to the fields of the target struct value.
+// environment variable values to the fields of the target struct value.
+// Invalid values are reported as `*UsageError`.
 func (opts *Set) ApplyEnv(env map[string]string) error {
 	for _, opt := range opts.Options {
 		if opt.Env != "" {
 			if v, ok := env[opt.Env]; ok {
 				if err := opt.SetValue(v); err != nil {
-					return fmt.Errorf(
+					return &UsageError{Err: fmt.Errorf(
 						"while applying value from environment variable '%v', %w",
-						opt.Env, err)
+						opt.Env, err)}
 				}
+				opt.source = Source{Kind: EnvSource, Name: opt.Env}
 			}
 		}
 	}
@@ -112,117 +160,294 @@ func (opts *Set) ApplyEnv(env map[string]string) error {
 }
 
 // ApplyArgs scans through a parsed option set and applies the corresponding
-// command-line arguments to the fields of the target struct value.
+// command-line arguments to the fields of the target struct value. The error of
+// a deferred special flag is returned only after all other arguments have been
+// applied.
 func (opts *Set) ApplyArgs(args []string) error {
-	opt, remainingArgs, err := opts.applyArgsToOptions(args)
+	remainingArgs, remainingIndexes, deferredErr, err := opts.applyArgsToOptions(args)
 	if err != nil {
 		return err
 	}
-	if opt != nil {
-		return fmt.Errorf("missing argument for '%v'", opt.Name())
-	}
 
+	// A slice positional argument receives all the arguments not bound to the
+	// other positional arguments, before or after it.
+	var fixedCount = len(opts.Positional)
+	if opts.slicePositional() != nil {
+		fixedCount--
+	}
 	for _, opt := range opts.Positional {
-		if len(remainingArgs) == 0 {
-			if opt.Optional {
+		var n = 1
+		if opt.Type == Slice {
+			// Missing values for the fixed positional arguments are reported
+			// against those arguments rather than against the slice.
+			n = len(remainingArgs) - fixedCount
+			if n < 0 {
+				n = 0
+			} else if err := opt.checkCount(n, deferredErr != nil); err != nil {
+				return err
+			}
+		} else {
+			fixedCount--
+		}
+		if len(remainingArgs) < n {
+			if opt.Optional || deferredErr != nil {
 				break
 			}
-			return fmt.Errorf("missing argument for '%v'", opt.Name())
+			return &UsageError{Err: fmt.Errorf("missing argument for '%v'", opt.Name())}
 		}
-		if err := opt.SetValue(remainingArgs[0]); err != nil {
-			return err
+		for i := 0; i < n; i++ {
+			if err := opt.SetValue(remainingArgs[i]); err != nil {
+				return &UsageError{Err: err}
+			}
+			opt.source = Source{Kind: ArgSource, Index: remainingIndexes[i]}
 		}
-		remainingArgs = remainingArgs[1:]
+		remainingArgs = remainingArgs[n:]
+		remainingIndexes = remainingIndexes[n:]
 	}
 
+	if opts.Args != nil {
+		if err := opts.Args.checkCount(len(remainingArgs), deferredErr != nil); err != nil {
+			return err
+		}
+	}
 	if len(remainingArgs) != 0 {
 		if opts.Args != nil {
-			for _, arg := range remainingArgs {
+			for i, arg := range remainingArgs {
 				if err := opts.Args.SetValue(arg); err != nil {
-					return err
+					return &UsageError{Err: err}
 				}
+				opts.Args.source = Source{Kind: ArgSource, Index: remainingIndexes[i]}
 			}
 		} else {
-			return fmt.Errorf(
+			return &UsageError{Err: fmt.Errorf(
 				"unsupported extra arguments: %v",
-				strings.Join(remainingArgs, " "))
+				strings.Join(remainingArgs, " "))}
 		}
 	}
+	return deferredErr
+}
 
-	return nil
+// SplitCommandArgs splits the command-line arguments `args` at the first
+// non-option argument, typically the name of a sub-command. Values of flags
+// expecting one are skipped over and kept with the flags. The second slice
+// returned starts with the first non-option argument, or is empty if none is
+// found.
+func (opts *Set) SplitCommandArgs(args []string) (flags, rest []string) {
+	var expectValue = false
+	for i, arg := range args {
+		if expectValue {
+			expectValue = false
+
+		} else if arg == "--" {
+			return args[:i], args[i+1:]
+
+		} else if strings.HasPrefix(arg, "--") {
+			var optName = arg[2:]
+			if strings.IndexByte(optName, '=') < 0 {
+				var opt = opts.GetOption(optName)
+				expectValue = opt != nil && opt.Type != Bool && opt.Type != Special &&
+					opt.Type != Count && !opt.OptionalValue
+			}
+
+		} else if strings.HasPrefix(arg, "-") {
+			arg = arg[1:]
+			for i, c := range arg {
+				var opt = opts.GetOption(string(c))
+				if opt == nil {
+					break
+				}
+				if opt.Type != Bool && opt.Type != Special && opt.Type != Count {
+					expectValue = len(arg[i+1:]) == 0 && !opt.OptionalValue
+					break
+				}
+			}
+
+		} else {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
 }
 
-func (opts *Set) applyArgsToOptions(
-	args []string) (
-	opt *T, remainingArgs []string, err error) {
+// argToken is an element of the command-line recognized by `scanArgs()`: a
+// flag naming an option, with its value if any, or a positional argument.
+type argToken struct {
+	opt      *T     // option named by the flag, nil for a positional argument
+	value    string // value given to the flag, or the positional argument
+	hasValue bool   // set to true if a value was given to the flag
+	negated  bool   // set for the `--no-` form of a negatable flag
+	index    int    // index in the command-line of the argument holding the value
+}
 
+// scanArgs splits the command-line arguments `args` into flags, with their
+// values, and positional arguments, and calls `f` for each of them, in order.
+// Flags expecting a value take the attached value or the next argument, and
+// all arguments following `--` are positional. Scanning stops at the first
+// invalid flag, missing value, or error returned by `f`.
+func (opts *Set) scanArgs(args []string, f func(t argToken) error) error {
+	var opt *T
 	for i, arg := range args {
 		if opt != nil {
-			if err := opt.SetValue(arg); err != nil {
-				return nil, nil, err
+			if err := f(argToken{opt: opt, value: arg, hasValue: true, index: i}); err != nil {
+				return err
 			}
 			opt = nil
 
 		} else if arg == "--" {
-			remainingArgs = append(remainingArgs, args[i+1:]...)
-			return
+			for j := i + 1; j < len(args); j++ {
+				if err := f(argToken{value: args[j], index: j}); err != nil {
+					return err
+				}
+			}
+			return nil
 
 		} else if strings.HasPrefix(arg, "--") {
-			optName := arg[2:]
-			valuePart := ""
+			var t = argToken{index: i}
+			var optName = arg[2:]
 			if i := strings.IndexByte(optName, '='); i >= 0 {
-				valuePart = optName[i:]
+				t.value, t.hasValue = optName[i+1:], true
 				optName = optName[:i]
 			}
-			opt = opts.GetOption(optName)
-			if opt == nil {
-				return nil, nil, &ErrInvalidFlag{arg}
+			t.opt = opts.GetOption(optName)
+			if neg := opts.negatedOption(optName); t.opt == nil && neg != nil {
+				if t.hasValue {
+					return &UsageError{Err: fmt.Errorf(
+						"flag '--%v' does not accept a value", optName)}
+				}
+				t.opt, t.negated = neg, true
 			}
-			if opt.Type == Special {
-				return nil, nil, opt.SpecialErr
+			if matches := opts.abbreviationMatches(optName); t.opt == nil && len(matches) > 1 {
+				return &UsageError{Err: &ErrAmbiguousFlag{
+					Flag:       arg,
+					Candidates: flagNames(matches),
+				}}
 			}
-			if opt.Type == Bool && valuePart == "" {
-				opt.SetBool()
-				opt = nil
+			if t.opt == nil {
+				return &UsageError{Err: &ErrInvalidFlag{
+					Flag:        arg,
+					Suggestions: opts.suggestFlags(optName, false),
+				}}
 			}
-			if valuePart != "" {
-				if err := opt.SetValue(valuePart[1:]); err != nil {
-					return nil, nil, err
-				}
-				opt = nil
+			if !t.hasValue && t.opt.OptionalValue {
+				t.value, t.hasValue = t.opt.ImplicitValue, true
+			}
+			if !t.hasValue && !t.negated && t.opt.expectsValue() {
+				opt = t.opt
+				continue
+			}
+			if err := f(t); err != nil {
+				return err
 			}
+
 		} else if strings.HasPrefix(arg, "-") {
 			arg = arg[1:]
-			for i, c := range arg {
-				opt = opts.GetOption(string(c))
-				if opt == nil {
-					return nil, nil, &ErrInvalidFlag{"-" + string(c)}
-				}
-				if opt.Type == Special {
-					return nil, nil, opt.SpecialErr
+			for j, c := range arg {
+				var t = argToken{opt: opts.GetOption(string(c)), index: i}
+				if t.opt == nil {
+					var err = &ErrInvalidFlag{Flag: "-" + string(c)}
+					if len(arg) > 1 {
+						// Possibly a long flag with a single dash
+						err.Suggestions = opts.suggestFlags(arg, true)
+					}
+					return &UsageError{Err: err}
 				}
-				if opt.Type == Bool {
-					opt.SetBool()
-					opt = nil
-				} else {
-					value := arg[i+1:]
-					if len(value) > 0 {
-						if err := opt.SetValue(value); err != nil {
-							return nil, nil, err
-						}
-						opt = nil
+				if t.opt.expectsValue() {
+					if value := arg[j+1:]; len(value) > 0 {
+						t.value, t.hasValue = value, true
+					} else if t.opt.OptionalValue {
+						t.value, t.hasValue = t.opt.ImplicitValue, true
+					} else {
+						opt = t.opt
+						break
 					}
+				}
+				if err := f(t); err != nil {
+					return err
+				}
+				if t.hasValue {
 					break
 				}
 			}
-		} else {
-			remainingArgs = append(remainingArgs, arg)
+
+		} else if err := f(argToken{value: arg, index: i}); err != nil {
+			return err
 		}
 	}
 
+	if opt != nil {
+		return &UsageError{Err: fmt.Errorf("missing argument for '%v'", opt.Name())}
+	}
+	return nil
+}
+
+// expectsValue returns true if the flag of the option takes a value.
+func (opt *T) expectsValue() bool {
+	return opt.Type != Bool && opt.Type != Special && opt.Type != Count
+}
+
+// applyArgsToOptions applies the flags in `args` to their options, and returns
+// the positional arguments, along with their index in `args`. The error of a
+// deferred special flag is returned separately, and scanning stops at the
+// first special flag that is not deferred.
+func (opts *Set) applyArgsToOptions(
+	args []string) (
+	remainingArgs []string, remainingIndexes []int, deferredErr error, err error) {
+
+	err = opts.scanArgs(args, func(t argToken) error {
+		switch {
+		case t.opt == nil:
+			remainingArgs = append(remainingArgs, t.value)
+			remainingIndexes = append(remainingIndexes, t.index)
+			return nil
+		case t.opt.Type == Special:
+			if !t.opt.Deferred {
+				return t.opt.SpecialErr
+			}
+			deferredErr = t.opt.SpecialErr
+			return nil
+		case t.negated:
+			t.opt.setBool(false)
+		case t.opt.Type == Bool && !t.hasValue:
+			t.opt.SetBool()
+		case t.opt.Type == Count && !t.hasValue:
+			t.opt.Increment()
+		default:
+			if err := t.opt.SetValue(t.value); err != nil {
+				return &UsageError{Err: err}
+			}
+		}
+		t.opt.source = Source{Kind: ArgSource, Index: t.index}
+		return nil
+	})
 	return
 }
 
+// parseSectionTag returns the section title defined by the `opts` tag of an
+// embedded struct field, which can only contain a `section:` field.
+func parseSectionTag(f reflect.StructField) (section string, err error) {
+	var s = f.Tag.Get("opts")
+	for len(s) > 0 {
+		var k, v string
+		k, v, s = scanTagFields(s)
+		if k != "section" {
+			return "", fmt.Errorf(
+				"invalid tag in opts of embedded field '%v': '%v'", f.Name, k)
+		}
+		section = v
+	}
+	return
+}
+
+// slicePositional returns the positional argument of slice type, if any.
+func (opts *Set) slicePositional() *T {
+	for _, opt := range opts.Positional {
+		if opt.Type == Slice {
+			return opt
+		}
+	}
+	return nil
+}
+
 // ---------------------------------------------------------------------------
 // Private support functions for NewOptionSet()
 // ---------------------------------------------------------------------------
@@ -289,18 +514,86 @@ func (opts *Set) parseStruct() error {
 			opts.Args.Name(), opts.Args.FieldType)
 	}
 
+	for _, opt := range opts.Options {
+		if opt.Negatable && (opt.Type != Bool || opt.Long == "") {
+			return fmt.Errorf(
+				"field '%v' of type '%v' cannot be negatable, bool with long flag expected",
+				opt.FieldName, opt.FieldType)
+		}
+		if opt.OptionalValue && (opt.Type == Bool || opt.Type == Count) {
+			return fmt.Errorf(
+				"field '%v' of type '%v' cannot have an optional value, %v flags take no value",
+				opt.FieldName, opt.FieldType, opt.Type)
+		}
+	}
+
+	var configFile *T
+	for _, opt := range opts.Options {
+		if opt.ConfigFile {
+			if configFile != nil {
+				return fmt.Errorf(
+					"multiple fields naming the configuration file: '%v' and '%v'",
+					configFile.FieldName, opt.FieldName,
+				)
+			}
+			configFile = opt
+		}
+	}
+	if configFile != nil && configFile.Type != Value && configFile.Type != Ptr {
+		return fmt.Errorf(
+			"field '%v' of type '%v' cannot name the configuration file",
+			configFile.Name(), configFile.FieldType)
+	}
+
+	var slicePositional *T
 	for _, arg := range opts.Positional {
+		if arg.Type == Slice && slicePositional != nil {
+			return fmt.Errorf(
+				"multiple slice positional arguments: '%v' and '%v'",
+				slicePositional.Name(), arg.Name())
+		}
+		if arg.Type == Slice && opts.Args != nil {
+			return fmt.Errorf(
+				"slice positional argument '%v' cannot be combined with '%v'",
+				arg.Name(), opts.Args.Name())
+		}
 		if arg.Type == Slice {
+			slicePositional = arg
+			continue
+		}
+		if arg.Type == Map {
 			return fmt.Errorf(
-				"field '%v' of type '%v' cannot be a slice to receive positional argument",
-				arg.Name(), arg.FieldType)
+				"field '%v' of type '%v' cannot be a %v to receive positional argument",
+				arg.Name(), arg.FieldType, arg.Type)
 
 		}
+		if arg.OptionalValue {
+			return fmt.Errorf(
+				"positional argument '%v' cannot have an optional value", arg.Name())
+		}
+	}
+
+	if err := opts.parseGroups(); err != nil {
+		return err
+	}
+
+	for _, opt := range opts.all() {
+		var isPositional = opt.Position != 0 || opt.Args
+		if (opt.MinCount != 0 || opt.MaxCount != 0) && (opt.Type != Slice || !isPositional) {
+			return fmt.Errorf(
+				"min-count: and max-count: tags not supported on field '%v' of type '%v'",
+				opt.FieldName, opt.FieldType)
+		}
+		if opt.MaxCount != 0 && opt.MaxCount < opt.MinCount {
+			return fmt.Errorf(
+				"max-count: of field '%v' is less than its min-count:", opt.FieldName)
+		}
 	}
 
 	// Traverse positional arguments backward and mark all trailing pointer type
-	// positional as optional.
-	for i := len(opts.Positional) - 1; i >= 0; i-- {
+	// positional as optional, unless a slice positional argument receives all
+	// the arguments not bound to the others.
+	for i := len(opts.Positional) - 1; i >= 0 && slicePositional == nil; i-- {
 		arg := opts.Positional[i]
 		if arg.FieldType.Kind() == reflect.Ptr {
 			arg.Optional = true
@@ -317,7 +610,12 @@ func (opts *Set) parseField(f reflect.StructField, index []int) error {
 	if f.Anonymous && f.Type.Kind() == reflect.Struct {
 		var t = f.Type
 		var fieldIndex = mergeIndexes(index, f.Index)
+		var section, err = parseSectionTag(f)
+		if err != nil {
+			return err
+		}
 
+		var first = len(opts.Options)
 		for i := 0; i < t.NumField(); i++ {
 			field := t.Field(i)
 			err := opts.parseField(field, fieldIndex)
@@ -325,6 +623,14 @@ func (opts *Set) parseField(f reflect.StructField, index []int) error {
 				return err
 			}
 		}
+
+		// Options of an embedded struct with a section title are listed under
+		// that title, unless they specify their own.
+		for _, opt := range opts.Options[first:] {
+			if opt.Section == "" {
+				opt.Section = section
+			}
+		}
 	} else {
 		tag, ok := f.Tag.Lookup("opts")
 		if !ok {
@@ -342,7 +648,18 @@ func (opts *Set) parseField(f reflect.StructField, index []int) error {
 			valueType = fieldType.Elem()
 			optionType = Ptr
 		}
-		if valueType.Kind() == reflect.Bool {
+		var keyType reflect.Type
+		if fieldType.Kind() == reflect.Map && !value.CanParseType(fieldType) {
+			keyType = fieldType.Key()
+			valueType = fieldType.Elem()
+			optionType = Map
+			if !value.CanParseType(keyType) {
+				return fmt.Errorf(
+					"key type '%v' of field '%v' is not parsable",
+					keyType, f.Name)
+			}
+		}
+		if valueType.Kind() == reflect.Bool && optionType != Map {
 			optionType = Bool
 		}
 
@@ -364,6 +681,7 @@ func (opts *Set) parseField(f reflect.StructField, index []int) error {
 			FieldName: f.Name,
 			FieldType: fieldType,
 			ValueType: valueType,
+			KeyType:   keyType,
 			Type:      optionType,
 			Index:     index,
 			opts:      opts,
@@ -372,6 +690,15 @@ func (opts *Set) parseField(f reflect.StructField, index []int) error {
 		if err != nil {
 			return err
 		}
+		if opt.Type == Count && (optionType != Value || !isIntegerKind(valueType.Kind()) ||
+			opt.Position != 0 || opt.Args) {
+			return fmt.Errorf(
+				"field '%v' of type '%v' cannot be a count, integer flag expected",
+				f.Name, fieldType)
+		}
+		if err := opt.parseConstraints(); err != nil {
+			return err
+		}
 
 		if desc, ok := f.Tag.Lookup("desc"); ok {
 			opt.Description = strings.TrimSpace(desc)
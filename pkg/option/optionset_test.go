@@ -137,7 +137,7 @@ func TestNewOptionSet_ArgN_BadType(t *testing.T) {
 	type argN struct {
 		Arg1 string   `opts:"arg:1"`
 		Arg2 []string `opts:"arg:2"`
-		Arg3 string   `opts:"arg:3"`
+		Arg3 []string `opts:"arg:3"`
 	}
 	var v argN
 	var opts, err = option.NewOptionSet(&v)
@@ -146,6 +146,133 @@ func TestNewOptionSet_ArgN_BadType(t *testing.T) {
 	require.That(t, opts).IsNil()
 }
 
+func TestNewOptionSet_ArgN_Slice(t *testing.T) {
+	type argN struct {
+		Arg1 string   `opts:"arg:1"`
+		Arg2 []string `opts:"arg:2, min-count:1, max-count:3"`
+		Arg3 string   `opts:"arg:3"`
+	}
+
+	var tcs = []struct {
+		args []string
+		arg1 string
+		arg2 []string
+		arg3 string
+		err  string
+	}{
+		{[]string{"a", "b", "c"}, "a", []string{"b"}, "c", ""},
+		{[]string{"a", "b", "c", "d", "e"}, "a", []string{"b", "c", "d"}, "e", ""},
+		{[]string{"a", "b"}, "", nil, "", "missing argument for '<arg2>...'"},
+		{[]string{"a"}, "", nil, "", "missing argument for '<arg3>'"},
+		{[]string{}, "", nil, "", "missing argument for '<arg1>'"},
+		{[]string{"a", "b", "c", "d", "e", "f"}, "", nil, "",
+			"too many arguments for '<arg2>...', at most 3 expected"},
+	}
+
+	for _, tc := range tcs {
+		var name = strings.Join(tc.args, " ")
+		t.Run(name, func(t *testing.T) {
+			var v argN
+			var opts, err = option.NewOptionSet(&v)
+			require.That(t, err).IsNil()
+
+			err = opts.ApplyArgs(tc.args)
+			if tc.err != "" {
+				require.That(t, err).ToString().Eq(tc.err)
+				return
+			}
+			require.That(t, err).IsNil()
+			require.That(t, v.Arg1).Eq(tc.arg1)
+			require.That(t, v.Arg2).Eq(tc.arg2)
+			require.That(t, v.Arg3).Eq(tc.arg3)
+			require.That(t, opts.Sources()["<arg2>..."].Index).Eq(len(tc.args) - 2)
+		})
+	}
+
+	bdd.Given(t, "an option set with a slice positional argument", func(t *bdd.T) {
+		var v argN
+		var opts, err = option.NewOptionSet(&v)
+		require.That(t, err).IsNil()
+
+		t.When("calling GetCompletion() with increasing number of arguments", func(t *bdd.T) {
+			var slots []string
+			for _, args := range [][]string{
+				{}, {"a"}, {"a", "b"}, {"a", "b", "c"}, {"a", "b", "c", "d"},
+			} {
+				slots = append(slots, opts.GetCompletion(args, "").ArgRef.Name())
+			}
+
+			t.Then("the slice receives arguments until its maximum count", func(t *bdd.T) {
+				require.That(t, slots).Eq([]string{
+					"<arg1>", "<arg2>...", "<arg2>...", "<arg2>...", "<arg3>",
+				})
+			})
+		})
+	})
+
+	bdd.Given(t, "a slice positional argument followed by a fixed one", func(t *bdd.T) {
+		type cpArgs struct {
+			Src []string `opts:"arg:1, name:src"`
+			Dst string   `opts:"arg:2, name:dst"`
+		}
+		var v cpArgs
+		var opts, err = option.NewOptionSet(&v)
+		require.That(t, err).IsNil()
+
+		t.When("calling ApplyArgs() without arguments", func(t *bdd.T) {
+			err := opts.ApplyArgs(nil)
+
+			t.Then("the missing fixed argument is reported", func(t *bdd.T) {
+				require.That(t, err).ToString().Eq("missing argument for '<dst>'")
+			})
+		})
+
+		t.When("calling ApplyArgs() with a single argument", func(t *bdd.T) {
+			err := opts.ApplyArgs([]string{"a"})
+
+			t.Then("the argument is bound to the fixed positional", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, v.Src).IsEmpty()
+				require.That(t, v.Dst).Eq("a")
+			})
+		})
+	})
+}
+
+func TestNewOptionSet_ArgN_SliceInvalid(t *testing.T) {
+	bdd.Given(t, "a slice positional argument combined with args", func(t *bdd.T) {
+		type argN struct {
+			Arg1 []string `opts:"arg:1"`
+			Args []string `opts:"args"`
+		}
+		_, err := option.NewOptionSet(&argN{})
+
+		t.Then("an error is returned", func(t *bdd.T) {
+			require.That(t, err).ToString().Contains("cannot be combined with '<args>...'")
+		})
+	})
+	bdd.Given(t, "a count tag on a non-slice positional argument", func(t *bdd.T) {
+		type argN struct {
+			Arg1 string `opts:"arg:1, min-count:2"`
+		}
+		_, err := option.NewOptionSet(&argN{})
+
+		t.Then("an error is returned", func(t *bdd.T) {
+			require.That(t, err).ToString().Contains("min-count: and max-count: tags not supported")
+		})
+	})
+	bdd.Given(t, "a max-count less than min-count", func(t *bdd.T) {
+		type argN struct {
+			Args []string `opts:"args, min-count:2, max-count:1"`
+		}
+		_, err := option.NewOptionSet(&argN{})
+
+		t.Then("an error is returned", func(t *bdd.T) {
+			require.That(t, err).ToString().Contains("less than its min-count")
+		})
+	})
+}
+
 func TestNewOptionSet_Arg0(t *testing.T) {
 	type arg0 struct {
 		Arg1 string `opts:"arg:1"`
@@ -524,6 +651,9 @@ func TestApplyArgs_Errors(t *testing.T) {
 			err = optionSet.ApplyArgs(tc.args)
 			require.That(t, err).IsNotNil()
 			require.That(t, err).ToString().Contains(tc.err)
+
+			_, isUsageErr := err.(*option.UsageError)
+			require.That(t, isUsageErr).IsTrue()
 		})
 	}
 }
@@ -661,3 +791,620 @@ func TestApplyArgs_NonFlagArguments_Errors(t *testing.T) {
 		})
 	}
 }
+
+// ---------------------------------------------------------------------------
+// OptionSet.SplitCommandArgs()
+// ---------------------------------------------------------------------------
+
+func TestSplitCommandArgs(t *testing.T) {
+	type command struct {
+		A bool          `opts:"-a, --aaa"`
+		D time.Duration `opts:"-d, --duration"`
+	}
+	var tcs = []struct {
+		args  []string
+		flags string
+		rest  string
+	}{
+		{[]string{"cmd", "-a"}, "", "cmd -a"},
+		{[]string{"-a", "cmd", "-d"}, "-a", "cmd -d"},
+		{[]string{"-d", "1m", "cmd"}, "-d 1m", "cmd"},
+		{[]string{"-ad", "1m", "cmd"}, "-ad 1m", "cmd"},
+		{[]string{"-ad1m", "cmd"}, "-ad1m", "cmd"},
+		{[]string{"--duration", "cmd", "sub"}, "--duration cmd", "sub"},
+		{[]string{"--duration=1m", "cmd"}, "--duration=1m", "cmd"},
+		{[]string{"-a", "--", "-d"}, "-a", "-d"},
+		{[]string{"-a", "--aaa"}, "-a --aaa", ""},
+	}
+
+	for _, tc := range tcs {
+		var name = strings.Join(tc.args, " ")
+		t.Run(name, func(t *testing.T) {
+			var cmd command
+			optionSet, err := option.NewOptionSet(&cmd)
+			require.That(t, err).IsNil()
+
+			flags, rest := optionSet.SplitCommandArgs(tc.args)
+			require.That(t, strings.Join(flags, " ")).Eq(tc.flags)
+			require.That(t, strings.Join(rest, " ")).Eq(tc.rest)
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// OptionSet.Sources()
+// ---------------------------------------------------------------------------
+
+func TestSources(t *testing.T) {
+	type command struct {
+		Host    string   `opts:"--host, default:localhost"`
+		Port    int      `opts:"-p, --port, default:80, env:PORT"`
+		Timeout string   `opts:"--timeout"`
+		Verbose bool     `opts:"-v, --verbose"`
+		Name    *string  `opts:"-n, --name"`
+		Target  string   `opts:"arg:1"`
+		Files   []string `opts:"args"`
+	}
+
+	bdd.Given(t, "an option set with values from every source", func(t *bdd.T) {
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+		config, err := option.ParseConfig("app.yaml", []byte("timeout: 5s\nport: 8080\n"))
+		require.That(t, err).IsNil()
+
+		require.That(t, optionSet.ApplyDefaults()).IsNil()
+		require.That(t, optionSet.ApplyConfig(config)).IsNil()
+		require.That(t, optionSet.ApplyEnv(map[string]string{"PORT": "9090"})).IsNil()
+
+		t.When("calling ApplyArgs()", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"-v", "--name", "foo", "v2", "a", "b"})
+			require.That(t, err).IsNil()
+			var sources = optionSet.Sources()
+
+			t.Then("the source of each value is recorded", func(t *bdd.T) {
+				require.That(t, sources["--host"]).Eq(option.Source{Kind: option.DefaultSource})
+				require.That(t, sources["--port"]).Eq(option.Source{Kind: option.EnvSource, Name: "PORT"})
+				require.That(t, sources["--timeout"]).Eq(option.Source{Kind: option.ConfigSource, Name: "app.yaml", Line: 1})
+				require.That(t, sources["--verbose"]).Eq(option.Source{Kind: option.ArgSource, Index: 0})
+				require.That(t, sources["--name"]).Eq(option.Source{Kind: option.ArgSource, Index: 2})
+				require.That(t, sources["<arg1>"]).Eq(option.Source{Kind: option.ArgSource, Index: 3})
+				require.That(t, sources["<args>..."]).Eq(option.Source{Kind: option.ArgSource, Index: 5})
+			})
+			t.Then("sources are formatted for display", func(t *bdd.T) {
+				require.That(t, sources["--host"].String()).Eq("default")
+				require.That(t, sources["--port"].String()).Eq("env PORT")
+				require.That(t, sources["--timeout"].String()).Eq("config app.yaml:1")
+				require.That(t, sources["--name"].String()).Eq("arg 2")
+			})
+			t.Then("values are formatted for display", func(t *bdd.T) {
+				require.That(t, optionSet.GetOption("port").FormatValue()).Eq("9090")
+				require.That(t, optionSet.GetOption("name").FormatValue()).Eq("foo")
+			})
+		})
+
+		t.When("no value is applied", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs([]string{"v2"})).IsNil()
+
+			t.Then("the option source is unset", func(t *bdd.T) {
+				var opt = optionSet.GetOption("name")
+				require.That(t, opt.Source().Kind).Eq(option.Unset)
+				require.That(t, opt.Source().String()).Eq("unset")
+				require.That(t, opt.FormatValue()).Eq("")
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// OptionSet.AllowAbbreviations
+// ---------------------------------------------------------------------------
+
+func TestAbbreviations(t *testing.T) {
+	type command struct {
+		Verbose bool   `opts:"-v, --verbose"`
+		Version bool   `opts:"--version"`
+		Output  string `opts:"-o, --output"`
+		Hidden  bool   `opts:"--outside"`
+	}
+
+	bdd.Given(t, "an option set with abbreviations allowed", func(t *bdd.T) {
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+		optionSet.AllowAbbreviations = true
+		optionSet.GetOption("outside").Hidden = true
+
+		t.When("calling ApplyArgs() with unambiguous prefixes", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--verb", "--out", "a.txt"})
+
+			t.Then("the matching flags are set", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, cmd.Verbose).IsTrue()
+				require.That(t, cmd.Output).Eq("a.txt")
+			})
+		})
+
+		t.When("calling ApplyArgs() with an ambiguous prefix", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--ver"})
+
+			t.Then("an error lists the candidates", func(t *bdd.T) {
+				require.That(t, err).ToString().Eq(
+					"ambiguous flag '--ver', could be one of '--verbose', '--version'")
+			})
+		})
+
+		t.When("calling GetOption() with a single character", func(t *bdd.T) {
+			var opt = optionSet.GetOption("o")
+
+			t.Then("only short flags are matched", func(t *bdd.T) {
+				require.That(t, opt).Field("Long").Eq("output")
+				require.That(t, optionSet.GetOption("e")).IsNil()
+			})
+		})
+
+		t.When("calling GetCompletion() after an abbreviated flag", func(t *bdd.T) {
+			completion := optionSet.GetCompletion([]string{"--out"}, "")
+
+			t.Then("the value of the flag is completed", func(t *bdd.T) {
+				require.That(t, completion.OptRef).Eq(optionSet.GetOption("output"))
+			})
+		})
+	})
+
+	bdd.Given(t, "an option set without abbreviations", func(t *bdd.T) {
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+
+		t.When("calling ApplyArgs() with a prefix", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--verb"})
+
+			t.Then("the flag is invalid", func(t *bdd.T) {
+				require.That(t, err).ToString().StartsWith("invalid flag '--verb'")
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Negatable bool options
+// ---------------------------------------------------------------------------
+
+func TestNegatableFlags(t *testing.T) {
+	type command struct {
+		Color   bool  `opts:"-c, --color, negatable, default:true"`
+		Verbose *bool `opts:"--verbose, negatable"`
+		Debug   bool  `opts:"--debug"`
+	}
+
+	bdd.Given(t, "an option set with negatable flags", func(t *bdd.T) {
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+		require.That(t, optionSet.ApplyDefaults()).IsNil()
+
+		t.When("calling ApplyArgs() with negated flags", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--no-color", "--no-verbose"})
+
+			t.Then("the flags are set to false", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, cmd.Color).IsFalse()
+				require.That(t, cmd.Verbose).IsNotNil()
+				require.That(t, *cmd.Verbose).IsFalse()
+				require.That(t, optionSet.GetOption("color").Source().Kind).Eq(option.ArgSource)
+			})
+		})
+
+		t.When("calling ApplyArgs() with a negated flag and a value", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--no-color=true"})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Eq("flag '--no-color' does not accept a value")
+			})
+		})
+
+		t.When("calling ApplyArgs() with a negated non-negatable flag", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--no-debug"})
+
+			t.Then("the flag is invalid", func(t *bdd.T) {
+				require.That(t, err).ToString().StartsWith("invalid flag '--no-debug'")
+			})
+		})
+
+		t.When("calling GetCompletion()", func(t *bdd.T) {
+			completion := optionSet.GetCompletion(nil, "")
+
+			t.Then("negated flags are suggested", func(t *bdd.T) {
+				require.That(t, completion.Options).IsEqualSet(
+					[]string{"--color", "--no-color", "--verbose", "--no-verbose", "--debug"})
+			})
+		})
+
+		t.When("calling GetCompletion() after a negated flag", func(t *bdd.T) {
+			completion := optionSet.GetCompletion([]string{"--no-color"}, "")
+
+			t.Then("the flag is no longer suggested", func(t *bdd.T) {
+				require.That(t, completion.Options).IsEqualSet(
+					[]string{"--verbose", "--no-verbose", "--debug"})
+			})
+		})
+	})
+
+	bdd.Given(t, "a struct with a negatable non-bool field", func(t *bdd.T) {
+		type command struct {
+			Port int `opts:"--port, negatable"`
+		}
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			_, err := option.NewOptionSet(&command{})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("cannot be negatable")
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Count options
+// ---------------------------------------------------------------------------
+
+func TestCountFlags(t *testing.T) {
+	type command struct {
+		Verbose int    `opts:"-v, --verbose, count"`
+		Level   uint8  `opts:"-l, count, env:LEVEL"`
+		X       bool   `opts:"-x"`
+		Name    string `opts:"-n"`
+	}
+	var tcs = []struct {
+		args    []string
+		verbose int
+		level   uint8
+	}{
+		{[]string{"-v"}, 1, 0},
+		{[]string{"-vvv"}, 3, 0},
+		{[]string{"-vvx", "-v"}, 3, 0},
+		{[]string{"--verbose", "-v", "--verbose"}, 3, 0},
+		{[]string{"--verbose=3"}, 3, 0},
+		{[]string{"--verbose=3", "-v"}, 4, 0},
+		{[]string{"-vlvl", "-nfoo"}, 2, 2},
+	}
+
+	for _, tc := range tcs {
+		var name = strings.Join(tc.args, " ")
+		t.Run(name, func(t *testing.T) {
+			var cmd command
+			optionSet, err := option.NewOptionSet(&cmd)
+			require.That(t, err).IsNil()
+
+			err = optionSet.ApplyArgs(tc.args)
+			require.That(t, err).IsNil()
+			require.That(t, cmd.Verbose).Eq(tc.verbose)
+			require.That(t, cmd.Level).Eq(tc.level)
+		})
+	}
+
+	bdd.Given(t, "an option set with count flags", func(t *bdd.T) {
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+
+		t.When("applying a count from the environment and arguments", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyEnv(map[string]string{"LEVEL": "2"})).IsNil()
+			require.That(t, optionSet.ApplyArgs([]string{"-ll"})).IsNil()
+
+			t.Then("arguments increment the value", func(t *bdd.T) {
+				require.That(t, cmd.Level).Eq(uint8(4))
+			})
+		})
+
+		t.When("calling GetCompletion() after a count flag", func(t *bdd.T) {
+			completion := optionSet.GetCompletion([]string{"-v"}, "")
+
+			t.Then("no value is expected and the flag is still suggested", func(t *bdd.T) {
+				require.That(t, completion.OptRef).IsNil()
+				require.That(t, completion.Options).IsSupersetOf(
+					[]string{"--verbose"})
+			})
+		})
+
+		t.When("calling SplitCommandArgs() after a count flag", func(t *bdd.T) {
+			flags, rest := optionSet.SplitCommandArgs([]string{"-v", "cmd"})
+
+			t.Then("the next argument is not a value", func(t *bdd.T) {
+				require.That(t, flags).Eq([]string{"-v"})
+				require.That(t, rest).Eq([]string{"cmd"})
+			})
+		})
+	})
+
+	bdd.Given(t, "a struct with a count non-integer field", func(t *bdd.T) {
+		type command struct {
+			Verbose string `opts:"-v, count"`
+		}
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			_, err := option.NewOptionSet(&command{})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("cannot be a count")
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Map options
+
+func TestMapOptions(t *testing.T) {
+	type command struct {
+		Labels map[string]int     `opts:"-l, --label, env:LABELS"`
+		Limits map[string]float64 `opts:"--limit, kv-sep::, sep:;, max:10"`
+		Flags  map[string]bool    `opts:"--flag"`
+		Ports  map[uint16]string  `opts:"--port"`
+	}
+
+	bdd.Given(t, "an option set with map fields", func(t *bdd.T) {
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+
+		t.When("applying repeated flags", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--label", "a=1", "-l", "b=2", "--label=c=3"})
+
+			t.Then("all entries are added to the map", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, cmd.Labels).Eq(map[string]int{"a": 1, "b": 2, "c": 3})
+			})
+		})
+
+		t.When("applying a value from the environment", func(t *bdd.T) {
+			err := optionSet.ApplyEnv(map[string]string{"LABELS": "a=1, b=2"})
+
+			t.Then("the value is split into entries", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, cmd.Labels).Eq(map[string]int{"a": 1, "b": 2})
+			})
+		})
+
+		t.When("using custom separators", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--limit", "cpu:1.5;mem:4"})
+
+			t.Then("the value is split accordingly", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, cmd.Limits).Eq(map[string]float64{"cpu": 1.5, "mem": 4})
+			})
+		})
+
+		t.When("using non-string keys and bool values", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{
+				"--port", "80=http", "--flag", "debug=true", "--flag", "trace=false"})
+
+			t.Then("keys and values are parsed to their types", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, cmd.Ports).Eq(map[uint16]string{80: "http"})
+				require.That(t, cmd.Flags).Eq(map[string]bool{"debug": true, "trace": false})
+			})
+		})
+
+		t.When("applying an empty value", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs([]string{"-l", "a=1", "-l", ""})).IsNil()
+
+			t.Then("the map is reset", func(t *bdd.T) {
+				require.That(t, cmd.Labels).IsNil()
+			})
+		})
+
+		t.When("applying an entry without separator", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--label", "a"})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("missing '=' in map entry 'a'")
+			})
+		})
+
+		t.When("applying an invalid value", func(t *bdd.T) {
+			err := optionSet.ApplyArgs([]string{"--label", "a=x"})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("failed to set value for '--label'")
+			})
+		})
+
+		t.When("validating values", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs([]string{"--limit", "cpu:20"})).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("each value of the map is checked", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("greater than the maximum")
+			})
+		})
+
+		t.When("applying a configuration table", func(t *bdd.T) {
+			config, err := option.ParseConfig("config.yaml", []byte(""+
+				"label:\n"+
+				"  a: 1\n"+
+				"  b: 2\n"))
+			require.That(t, err).IsNil()
+			require.That(t, optionSet.ApplyArgs([]string{"-l", "c=3"})).IsNil()
+			err = optionSet.ApplyConfig(config)
+
+			t.Then("the table replaces the previous entries", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, cmd.Labels).Eq(map[string]int{"a": 1, "b": 2})
+			})
+		})
+
+		t.When("calling GetCompletion() after a map flag", func(t *bdd.T) {
+			completion := optionSet.GetCompletion([]string{"-l", "a=1"}, "")
+
+			t.Then("the flag is still suggested", func(t *bdd.T) {
+				require.That(t, completion.Options).IsSupersetOf(
+					[]string{"--label"})
+			})
+		})
+	})
+
+	bdd.Given(t, "a struct with a map field with a non-parsable key", func(t *bdd.T) {
+		type command struct {
+			Labels map[[2]int]string `opts:"--label"`
+		}
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			_, err := option.NewOptionSet(&command{})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("key type '[2]int'")
+			})
+		})
+	})
+
+	bdd.Given(t, "a struct with a positional map field", func(t *bdd.T) {
+		type command struct {
+			Labels map[string]string `opts:"arg:1"`
+		}
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			_, err := option.NewOptionSet(&command{})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("cannot be a map")
+			})
+		})
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Optional-value flags
+
+func TestOptionalValueFlags(t *testing.T) {
+	type command struct {
+		Color   string   `opts:"-c, --color, optional-value:always, default:auto, name:when"`
+		Level   *int     `opts:"-l, --level, optional-value:1"`
+		Verbose bool     `opts:"-v, --verbose"`
+		Args    []string `opts:"args"`
+	}
+
+	var tcs = []struct {
+		args  []string
+		color string
+		level *int
+		rest  []string
+	}{
+		{[]string{}, "auto", nil, nil},
+		{[]string{"--color"}, "always", nil, nil},
+		{[]string{"--color", "never"}, "always", nil, []string{"never"}},
+		{[]string{"--color=never"}, "never", nil, nil},
+		{[]string{"-c"}, "always", nil, nil},
+		{[]string{"-c", "never"}, "always", nil, []string{"never"}},
+		{[]string{"-cnever"}, "never", nil, nil},
+		{[]string{"-vc"}, "always", nil, nil},
+		{[]string{"--level", "arg"}, "auto", intPtr(1), []string{"arg"}},
+		{[]string{"--level=3"}, "auto", intPtr(3), nil},
+	}
+
+	for _, tc := range tcs {
+		var name = strings.Join(tc.args, " ")
+		t.Run(name, func(t *testing.T) {
+			var cmd command
+			optionSet, err := option.NewOptionSet(&cmd)
+			require.That(t, err).IsNil()
+			require.That(t, optionSet.ApplyDefaults()).IsNil()
+
+			err = optionSet.ApplyArgs(tc.args)
+			require.That(t, err).IsNil()
+			require.That(t, cmd.Color).Eq(tc.color)
+			require.That(t, cmd.Level).Eq(tc.level)
+			require.That(t, cmd.Args).Eq(tc.rest)
+		})
+	}
+
+	bdd.Given(t, "an option set with optional-value flags", func(t *bdd.T) {
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+
+		t.When("calling GetCompletion() after a bare optional-value flag", func(t *bdd.T) {
+			completion := optionSet.GetCompletion([]string{"--color"}, "")
+
+			t.Then("no value is expected", func(t *bdd.T) {
+				require.That(t, completion.OptRef).IsNil()
+				require.That(t, completion.ArgRef).IsNotNil()
+			})
+		})
+
+		t.When("calling GetCompletion() with a partial value after '='", func(t *bdd.T) {
+			completion := optionSet.GetCompletion([]string{}, "--color=ne")
+
+			t.Then("the value of the flag is completed", func(t *bdd.T) {
+				require.That(t, completion.OptRef).Eq(optionSet.GetOption("color"))
+				require.That(t, completion.OptPrefix).Eq("--color=")
+			})
+		})
+
+		t.When("calling SplitCommandArgs() after a bare optional-value flag", func(t *bdd.T) {
+			flags, rest := optionSet.SplitCommandArgs([]string{"--color", "cmd"})
+
+			t.Then("the next argument is not a value", func(t *bdd.T) {
+				require.That(t, flags).Eq([]string{"--color"})
+				require.That(t, rest).Eq([]string{"cmd"})
+			})
+		})
+	})
+
+	bdd.Given(t, "a struct with an optional-value bool field", func(t *bdd.T) {
+		type command struct {
+			Verbose bool `opts:"-v, optional-value:true"`
+		}
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			_, err := option.NewOptionSet(&command{})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains("cannot have an optional value")
+			})
+		})
+	})
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// ---------------------------------------------------------------------------
+// Usage sections
+
+func TestSections(t *testing.T) {
+	type network struct {
+		Host  string `opts:"--host"`
+		Proxy string `opts:"--proxy, section:Proxy options"`
+	}
+	type command struct {
+		network `opts:"section:Network options"`
+		Output  string `opts:"-o, --output, section:Output options"`
+		Verbose bool   `opts:"-v, --verbose"`
+	}
+
+	bdd.Given(t, "a struct with sections", func(t *bdd.T) {
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			optionSet, err := option.NewOptionSet(&command{})
+
+			t.Then("options record their section", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, optionSet.Options).Field("Section").Eq([]string{
+					"Network options", "Proxy options", "Output options", "",
+				})
+			})
+		})
+	})
+
+	bdd.Given(t, "an embedded struct with an invalid tag", func(t *bdd.T) {
+		type command struct {
+			network `opts:"--network"`
+		}
+		t.When("calling NewOptionSet()", func(t *bdd.T) {
+			_, err := option.NewOptionSet(&command{})
+
+			t.Then("an error is returned", func(t *bdd.T) {
+				require.That(t, err).ToString().Contains(
+					"invalid tag in opts of embedded field 'network'")
+			})
+		})
+	})
+}
@@ -0,0 +1,106 @@
+package option
+
+// Schema is a machine-readable representation of all the options defined in
+// an option set, suitable for JSON serialization. Hidden options are omitted.
+type Schema struct {
+	Options    []OptionSchema `json:"options"`
+	Positional []OptionSchema `json:"positional"`
+	Args       *OptionSchema  `json:"args,omitempty"`
+}
+
+// OptionSchema is a machine-readable representation of a single option,
+// suitable for JSON serialization.
+type OptionSchema struct {
+	Name        string   `json:"name"`
+	Short       string   `json:"short,omitempty"`
+	Long        string   `json:"long,omitempty"`
+	Kind        string   `json:"kind"`
+	Type        string   `json:"type,omitempty"`
+	KeyType     string   `json:"keyType,omitempty"`
+	ValueName   string   `json:"valueName,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Env         string   `json:"env,omitempty"`
+	Sep         string   `json:"sep,omitempty"`
+	KVSep       string   `json:"kvSep,omitempty"`
+	Position    int      `json:"position,omitempty"`
+	Optional    bool     `json:"optional,omitempty"`
+	Args        bool     `json:"args,omitempty"`
+	MinCount    int      `json:"minCount,omitempty"`
+	MaxCount    int      `json:"maxCount,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Min         string   `json:"min,omitempty"`
+	Max         string   `json:"max,omitempty"`
+	Choices     []string `json:"choices,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Exclusive   string   `json:"exclusive,omitempty"`
+	Together    string   `json:"together,omitempty"`
+	OneRequired string   `json:"oneRequired,omitempty"`
+	Negatable   bool     `json:"negatable,omitempty"`
+	Section     string   `json:"section,omitempty"`
+	Implicit    *string  `json:"implicit,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Schema returns a machine-readable representation of the option set. The
+// options are listed in the order in which they are defined.
+func (opts *Set) Schema() Schema {
+	var schema = Schema{
+		Options:    []OptionSchema{},
+		Positional: []OptionSchema{},
+	}
+	for _, opt := range opts.Options {
+		if !opt.Hidden {
+			schema.Options = append(schema.Options, opt.Schema())
+		}
+	}
+	for _, opt := range opts.Positional {
+		schema.Positional = append(schema.Positional, opt.Schema())
+	}
+	if opts.Args != nil {
+		var args = opts.Args.Schema()
+		schema.Args = &args
+	}
+	return schema
+}
+
+// Schema returns a machine-readable representation of the option.
+func (opt *T) Schema() OptionSchema {
+	var schema = OptionSchema{
+		Name:        opt.Name(),
+		Short:       opt.Short,
+		Long:        opt.Long,
+		Kind:        opt.Type.String(),
+		ValueName:   opt.ValueName,
+		Default:     opt.Default,
+		Env:         opt.Env,
+		Sep:         opt.Sep,
+		KVSep:       opt.KVSep,
+		Position:    opt.Position,
+		Optional:    opt.Optional,
+		Args:        opt.Args,
+		MinCount:    opt.MinCount,
+		MaxCount:    opt.MaxCount,
+		Required:    opt.Required,
+		Min:         opt.Min,
+		Max:         opt.Max,
+		Choices:     opt.Choices,
+		Pattern:     opt.Pattern,
+		Exclusive:   opt.Exclusive,
+		Together:    opt.Together,
+		OneRequired: opt.OneRequired,
+		Negatable:   opt.Negatable,
+		Section:     opt.Section,
+		Description: opt.Description,
+	}
+	if opt.ValueType != nil {
+		schema.Type = opt.ValueType.String()
+	}
+	if opt.OptionalValue {
+		var implicit = opt.ImplicitValue
+		schema.Implicit = &implicit
+	}
+	if opt.KeyType != nil {
+		schema.KeyType = opt.KeyType.String()
+	}
+	return schema
+}
@@ -0,0 +1,70 @@
+package option_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/maargenton/go-testpredicate/pkg/bdd"
+	"github.com/maargenton/go-testpredicate/pkg/require"
+
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+func TestSetSchema(t *testing.T) {
+	bdd.Given(t, "an option set with flags and arguments", func(t *bdd.T) {
+		type command struct {
+			Period time.Duration `opts:"-d,--duration, default:5m, env:PERIOD" desc:"period"`
+			Tags   []string      `opts:"-t, --tag, sep:\\,, name:tag"`
+			Port   string        `opts:"arg:1, name:port"`
+			Aux    *string       `opts:"arg:2"`
+			Inputs []string      `opts:"args, name:inputs"`
+		}
+		var cmd command
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+		optionSet.AddSpecialFlag("h", "help", "display usage", ErrSpecialFlag)
+		optionSet.AddSpecialFlag("", "hidden", "", ErrSpecialFlag2).Hidden = true
+
+		t.When("calling Schema()", func(t *bdd.T) {
+			var schema = optionSet.Schema()
+
+			t.Then("all visible options are described in order", func(t *bdd.T) {
+				require.That(t, schema.Options).Field("Name").Eq(
+					[]string{"--duration", "--tag", "--help"})
+				require.That(t, schema.Options[0]).Eq(option.OptionSchema{
+					Name:        "--duration",
+					Short:       "d",
+					Long:        "duration",
+					Kind:        "value",
+					Type:        "time.Duration",
+					Default:     "5m",
+					Env:         "PERIOD",
+					Description: "period",
+				})
+				require.That(t, schema.Options[1].Kind).Eq("slice")
+				require.That(t, schema.Options[1].Sep).Eq(",")
+				require.That(t, schema.Options[2].Kind).Eq("special")
+			})
+			t.Then("positional arguments are described", func(t *bdd.T) {
+				require.That(t, schema.Positional).Length().Eq(2)
+				require.That(t, schema.Positional[0].Position).Eq(1)
+				require.That(t, schema.Positional[0].ValueName).Eq("port")
+				require.That(t, schema.Positional[1].Optional).IsTrue()
+				require.That(t, schema.Args).IsNotNil()
+				require.That(t, schema.Args.Args).IsTrue()
+			})
+		})
+
+		t.When("serializing the schema to JSON", func(t *bdd.T) {
+			d, err := json.Marshal(optionSet.Schema().Options[0])
+
+			t.Then("the document uses stable field names", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+				require.That(t, string(d)).Eq(`{"name":"--duration","short":"d",` +
+					`"long":"duration","kind":"value","type":"time.Duration",` +
+					`"default":"5m","env":"PERIOD","description":"period"}`)
+			})
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package option
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// SourceKind describes where the value of an option came from.
+type SourceKind int
+
+// Contant values for SourceKind
+const (
+	Unset SourceKind = iota
+	DefaultSource
+	ConfigSource
+	EnvSource
+	ArgSource
+)
+
+// String returns a lower-case name for the source kind.
+func (k SourceKind) String() string {
+	switch k {
+	case Unset:
+		return "unset"
+	case DefaultSource:
+		return "default"
+	case ConfigSource:
+		return "config"
+	case EnvSource:
+		return "env"
+	case ArgSource:
+		return "arg"
+	}
+	return "SourceKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Source records where the value of an option came from. `Name` is the name
+// of the environment variable or the configuration file, `Line` the line in
+// the configuration file if known, and `Index` the index of the command-line
+// argument holding the value within the arguments passed to `ApplyArgs()`.
+type Source struct {
+	Kind  SourceKind
+	Name  string
+	Line  int
+	Index int
+}
+
+// String returns a human-readable description of the source.
+func (s Source) String() string {
+	switch s.Kind {
+	case EnvSource:
+		return fmt.Sprintf("env %v", s.Name)
+	case ConfigSource:
+		if s.Line > 0 {
+			return fmt.Sprintf("config %v:%v", s.Name, s.Line)
+		}
+		return fmt.Sprintf("config %v", s.Name)
+	case ArgSource:
+		return fmt.Sprintf("arg %v", s.Index)
+	}
+	return s.Kind.String()
+}
+
+// Source returns the source of the current value of the option, as recorded
+// by the last source successfully applied to it.
+func (opt *T) Source() Source {
+	return opt.source
+}
+
+// Sources returns the source of the current value of every option in the set,
+// including positional arguments, indexed by option name.
+func (opts *Set) Sources() map[string]Source {
+	var sources = make(map[string]Source)
+	for _, opt := range opts.all() {
+		if opt.Type != Special {
+			sources[opt.Name()] = opt.source
+		}
+	}
+	return sources
+}
+
+// FormatValue returns a string representation of the current value of the
+// field backing the option, or an empty string for nil pointers.
+func (opt *T) FormatValue() string {
+	var fv = opt.opts.target.FieldByIndex(opt.Index)
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return ""
+		}
+		fv = fv.Elem()
+	}
+	return formatValue(fv)
+}
+
+// formatValue returns a string representation of a value, using its
+// `String()` method if available.
+func formatValue(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// all returns all the options of the set, flags first, then positional
+// arguments and remaining arguments.
+func (opts *Set) all() []*T {
+	var all = append([]*T{}, opts.Options...)
+	all = append(all, opts.Positional...)
+	if opts.Args != nil {
+		all = append(all, opts.Args)
+	}
+	return all
+}
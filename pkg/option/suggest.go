@@ -0,0 +1,121 @@
+package option
+
+import (
+	"sort"
+	"strings"
+)
+
+// Suggest returns the candidates that are close enough to `name` to be
+// suggested as a correction, in order of increasing edit distance. Candidates
+// are considered close when their Levenshtein distance to `name` is at most
+// one third of the length of `name`, and no more than 2, or when `name` is a
+// prefix of the candidate.
+func Suggest(name string, candidates []string) []string {
+	type match struct {
+		candidate string
+		distance  int
+	}
+
+	var maxDistance = len(name) / 3
+	if maxDistance > 2 {
+		maxDistance = 2
+	}
+
+	var matches []match
+	var seen = make(map[string]struct{})
+	for _, c := range candidates {
+		if _, ok := seen[c]; ok || c == name {
+			continue
+		}
+		seen[c] = struct{}{}
+		var d = levenshtein(name, c)
+		if d <= maxDistance || (len(name) > 1 && strings.HasPrefix(c, name)) {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	var r []string
+	for _, m := range matches {
+		r = append(r, m.candidate)
+	}
+	return r
+}
+
+// levenshtein returns the edit distance between `a` and `b`, counted in runes.
+func levenshtein(a, b string) int {
+	var ra, rb = []rune(a), []rune(b)
+	var prev = make([]int, len(rb)+1)
+	var curr = make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			var cost = 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// suggestFlags returns the names of the flags of the set, with leading dashes,
+// that are close to the flag name `name`, given without leading dashes. If
+// `exact` is set, as for a long flag used with a single dash, a flag named
+// exactly `name` is the only suggestion.
+func (opts *Set) suggestFlags(name string, exact bool) []string {
+	var candidates []string
+	var flags = make(map[string]string)
+	for _, opt := range opts.Options {
+		if opt.Hidden {
+			continue
+		}
+		if opt.Long != "" {
+			candidates = append(candidates, opt.Long)
+			flags[opt.Long] = "--" + opt.Long
+		}
+		if opt.Negatable {
+			candidates = append(candidates, "no-"+opt.Long)
+			flags["no-"+opt.Long] = "--no-" + opt.Long
+		}
+		if opt.Short != "" {
+			candidates = append(candidates, opt.Short)
+			flags[opt.Short] = "-" + opt.Short
+		}
+	}
+
+	if f, ok := flags[name]; ok && exact {
+		return []string{f}
+	}
+
+	var r []string
+	for _, s := range Suggest(name, candidates) {
+		r = append(r, flags[s])
+	}
+	return r
+}
+
+func flagNames(options []*T) []string {
+	var names []string
+	for _, opt := range options {
+		names = append(names, opt.Name())
+	}
+	return names
+}
@@ -0,0 +1,70 @@
+package option_test
+
+import (
+	"testing"
+
+	"github.com/maargenton/go-testpredicate/pkg/require"
+
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+func TestSuggest(t *testing.T) {
+	var candidates = []string{"verbose", "version", "format", "force", "output"}
+	var tcs = []struct {
+		name        string
+		suggestions []string
+	}{
+		{"verbos", []string{"verbose"}},
+		{"vrebose", []string{"verbose"}},
+		{"versoin", []string{"version"}},
+		{"ver", []string{"verbose", "version"}},
+		{"forse", []string{"force"}},
+		{"xyz", nil},
+		{"f", nil},
+		{"format", nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.That(t, option.Suggest(tc.name, candidates)).Eq(tc.suggestions)
+		})
+	}
+}
+
+func TestApplyArgs_InvalidFlagSuggestions(t *testing.T) {
+	type command struct {
+		Verbose bool   `opts:"-v, --verbose"`
+		Version bool   `opts:"--version"`
+		Output  string `opts:"-o, --output"`
+	}
+	var tcs = []struct {
+		args        []string
+		suggestions []string
+		err         string
+	}{
+		{[]string{"--verbos"}, []string{"--verbose"}, "invalid flag '--verbos', did you mean '--verbose'?"},
+		{[]string{"--ver"}, []string{"--verbose", "--version"}, "invalid flag '--ver', did you mean one of '--verbose', '--version'?"},
+		{[]string{"--output=a", "--outptu=b"}, []string{"--output"}, "invalid flag '--outptu=b', did you mean '--output'?"},
+		{[]string{"-verbos"}, []string{"--verbose"}, "invalid flag '-e', did you mean '--verbose'?"},
+		{[]string{"-verbose"}, []string{"--verbose"}, "invalid flag '-e', did you mean '--verbose'?"},
+		{[]string{"--xyz"}, nil, "invalid flag '--xyz'"},
+		{[]string{"-x"}, nil, "invalid flag '-x'"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.err, func(t *testing.T) {
+			var cmd command
+			optionSet, err := option.NewOptionSet(&cmd)
+			require.That(t, err).IsNil()
+
+			err = optionSet.ApplyArgs(tc.args)
+			require.That(t, err).ToString().Eq(tc.err)
+
+			usageErr, ok := err.(*option.UsageError)
+			require.That(t, ok).IsTrue()
+			flagErr, ok := usageErr.Err.(*option.ErrInvalidFlag)
+			require.That(t, ok).IsTrue()
+			require.That(t, flagErr.Suggestions).Eq(tc.suggestions)
+		})
+	}
+}
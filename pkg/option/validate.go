@@ -0,0 +1,180 @@
+package option
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/maargenton/go-cli/pkg/value"
+)
+
+// Validate checks the values of all the options of the set against the
+// constraints defined by the `required`, `min:`, `max:`, `choices:` and
+// `pattern:` tags, and the rules of all option groups. It is meant to be called
+// after all the sources have been applied, and returns an `*ErrValidation`
+// listing all the violations found.
+func (opts *Set) Validate() error {
+	var errs []error
+	for _, opt := range opts.all() {
+		if opt.Type == Special {
+			continue
+		}
+		errs = append(errs, opt.validate()...)
+	}
+	errs = append(errs, opts.checkGroups()...)
+	if len(errs) != 0 {
+		return &ErrValidation{Errors: errs}
+	}
+	return nil
+}
+
+// validate returns all the constraint violations of the current value of the
+// option. Every value of slice and map options is checked individually.
+func (opt *T) validate() (errs []error) {
+	if opt.Required && opt.source.Kind == Unset {
+		errs = append(errs, fmt.Errorf("missing required value for '%v'", opt.Name()))
+	}
+
+	var fv = opt.opts.target.FieldByIndex(opt.Index)
+	var values []reflect.Value
+	switch {
+	case opt.Type == Slice:
+		for i := 0; i < fv.Len(); i++ {
+			values = append(values, fv.Index(i))
+		}
+	case opt.Type == Map:
+		var iter = fv.MapRange()
+		for iter.Next() {
+			values = append(values, iter.Value())
+		}
+	case fv.Kind() == reflect.Ptr && opt.ValueType != opt.FieldType:
+		if !fv.IsNil() {
+			values = append(values, fv.Elem())
+		}
+	default:
+		if opt.source.Kind != Unset {
+			values = append(values, fv)
+		}
+	}
+
+	for _, v := range values {
+		if err := opt.validateValue(v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+func (opt *T) validateValue(v reflect.Value) error {
+	if opt.min.IsValid() && compareValues(v, opt.min) < 0 {
+		return fmt.Errorf("value '%v' for '%v' is less than the minimum '%v'",
+			formatValue(v), opt.Name(), opt.Min)
+	}
+	if opt.max.IsValid() && compareValues(v, opt.max) > 0 {
+		return fmt.Errorf("value '%v' for '%v' is greater than the maximum '%v'",
+			formatValue(v), opt.Name(), opt.Max)
+	}
+	if len(opt.choices) != 0 {
+		var found = false
+		for _, c := range opt.choices {
+			if reflect.DeepEqual(v.Interface(), c.Interface()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value '%v' for '%v' is not one of '%v'",
+				formatValue(v), opt.Name(), strings.Join(opt.Choices, "', '"))
+		}
+	}
+	if opt.pattern != nil && !opt.pattern.MatchString(formatValue(v)) {
+		return fmt.Errorf("value '%v' for '%v' does not match pattern '%v'",
+			formatValue(v), opt.Name(), opt.Pattern)
+	}
+	return nil
+}
+
+// parseConstraints checks that the constraints defined in the `opts` tag are
+// compatible with the type of the option, and parses their values.
+func (opt *T) parseConstraints() (err error) {
+	if opt.Required && opt.Default != "" {
+		return fmt.Errorf(
+			"required and default: tags cannot be combined on field '%v'", opt.FieldName)
+	}
+	if opt.Min != "" || opt.Max != "" {
+		if !isOrderedKind(opt.ValueType.Kind()) {
+			return fmt.Errorf(
+				"min: and max: tags not supported on field '%v' of type '%v'",
+				opt.FieldName, opt.FieldType)
+		}
+	}
+	if opt.Min != "" {
+		if opt.min, err = parseConstraintValue(opt.ValueType, opt.Min); err != nil {
+			return fmt.Errorf("invalid min: value for field '%v', %w", opt.FieldName, err)
+		}
+	}
+	if opt.Max != "" {
+		if opt.max, err = parseConstraintValue(opt.ValueType, opt.Max); err != nil {
+			return fmt.Errorf("invalid max: value for field '%v', %w", opt.FieldName, err)
+		}
+	}
+	for _, c := range opt.Choices {
+		v, err := parseConstraintValue(opt.ValueType, c)
+		if err != nil {
+			return fmt.Errorf("invalid choices: value for field '%v', %w", opt.FieldName, err)
+		}
+		opt.choices = append(opt.choices, v)
+	}
+	if opt.Pattern != "" {
+		if opt.pattern, err = regexp.Compile(opt.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: value for field '%v', %w", opt.FieldName, err)
+		}
+	}
+	return nil
+}
+
+func parseConstraintValue(t reflect.Type, s string) (reflect.Value, error) {
+	var v = reflect.New(t)
+	if err := value.Parse(v.Interface(), s); err != nil {
+		return reflect.Value{}, err
+	}
+	return v.Elem(), nil
+}
+
+func isOrderedKind(k reflect.Kind) bool {
+	return isIntegerKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// compareValues compares two values of the same ordered kind, returning -1, 0
+// or 1.
+func compareValues(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compare(a.Int() < b.Int(), a.Int() > b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compare(a.Uint() < b.Uint(), a.Uint() > b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compare(a.Float() < b.Float(), a.Float() > b.Float())
+	}
+	return 0
+}
+
+func compare(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
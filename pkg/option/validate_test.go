@@ -0,0 +1,109 @@
+package option_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maargenton/go-testpredicate/pkg/bdd"
+	"github.com/maargenton/go-testpredicate/pkg/require"
+
+	"github.com/maargenton/go-cli/pkg/option"
+)
+
+type validateCommand struct {
+	Port    int           `opts:"-p, --port, min:1, max:65535, default:8080"`
+	Timeout time.Duration `opts:"--timeout, max:1m"`
+	Format  string        `opts:"-f, --format, choices:json|yaml|text"`
+	Name    *string       `opts:"-n, --name, required, pattern:^[a-z]+$"`
+	Tags    []string      `opts:"-t, --tag, pattern:^[a-z]+$"`
+	Target  *string       `opts:"arg:1, required"`
+}
+
+func TestValidate(t *testing.T) {
+	bdd.Given(t, "an option set with constraints", func(t *bdd.T) {
+		var cmd validateCommand
+		optionSet, err := option.NewOptionSet(&cmd)
+		require.That(t, err).IsNil()
+		require.That(t, optionSet.ApplyDefaults()).IsNil()
+
+		t.When("all values satisfy the constraints", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs([]string{
+				"-p", "80", "--timeout", "30s", "-f", "yaml", "-n", "foo", "-t", "a", "v2",
+			})).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("no error is returned", func(t *bdd.T) {
+				require.That(t, err).IsNil()
+			})
+		})
+
+		t.When("required values are missing", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs(nil)).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("all missing values are reported", func(t *bdd.T) {
+				var verr *option.ErrValidation
+				require.That(t, errors.As(err, &verr)).IsTrue()
+				require.That(t, verr.Errors).Length().Eq(2)
+				require.That(t, err).ToString().Contains("missing required value for '--name'")
+				require.That(t, err).ToString().Contains("missing required value for '<arg1>'")
+			})
+		})
+
+		t.When("values violate the constraints", func(t *bdd.T) {
+			require.That(t, optionSet.ApplyArgs([]string{
+				"-p", "0", "--timeout", "2m", "-f", "xml", "-n", "Foo", "-t", "a", "-t", "B", "v2",
+			})).IsNil()
+			err := optionSet.Validate()
+
+			t.Then("all violations are reported", func(t *bdd.T) {
+				var verr *option.ErrValidation
+				require.That(t, errors.As(err, &verr)).IsTrue()
+				require.That(t, verr.Errors).Length().Eq(5)
+				require.That(t, err).ToString().Contains("value '0' for '--port' is less than the minimum '1'")
+				require.That(t, err).ToString().Contains("value '2m0s' for '--timeout' is greater than the maximum '1m'")
+				require.That(t, err).ToString().Contains("value 'xml' for '--format' is not one of 'json', 'yaml', 'text'")
+				require.That(t, err).ToString().Contains("value 'Foo' for '--name' does not match pattern '^[a-z]+$'")
+				require.That(t, err).ToString().Contains("value 'B' for '--tag' does not match pattern '^[a-z]+$'")
+			})
+		})
+	})
+}
+
+func TestValidate_InvalidConstraints(t *testing.T) {
+	var tcs = []struct {
+		name string
+		cmd  interface{}
+		err  string
+	}{
+		{"min: on a string", &struct {
+			Name string `opts:"--name, min:a"`
+		}{}, "min: and max: tags not supported"},
+		{"invalid max: value", &struct {
+			Port int `opts:"--port, max:abc"`
+		}{}, "invalid max: value for field 'Port'"},
+		{"invalid choices: value", &struct {
+			Port int `opts:"--port, choices:1|abc"`
+		}{}, "invalid choices: value for field 'Port'"},
+		{"invalid pattern: value", &struct {
+			Name string `opts:"--name, pattern:[a-"`
+		}{}, "invalid pattern: value for field 'Name'"},
+		{"required and default:", &struct {
+			Name string `opts:"--name, required, default:x"`
+		}{}, "required and default: tags cannot be combined on field 'Name'"},
+	}
+
+	for _, tc := range tcs {
+		bdd.Given(t, "a struct with "+tc.name, func(t *bdd.T) {
+			t.When("calling NewOptionSet()", func(t *bdd.T) {
+				_, err := option.NewOptionSet(tc.cmd)
+
+				t.Then("an error is returned", func(t *bdd.T) {
+					require.That(t, err).IsNotNil()
+					require.That(t, err).ToString().Contains(tc.err)
+				})
+			})
+		})
+	}
+}
@@ -19,12 +19,12 @@ func init() {
 type WorkloadType uint8
 
 const (
-	Rsa2048 WorkloadType = iota
-	Rsa4096
-	EcdsaP256
-	EcdsaP284
-	EcdsaP521
-	Ed25519
+	Rsa2048   WorkloadType = iota // RSA signatures with 2048-bit keys
+	Rsa4096                       // RSA signatures with 4096-bit keys
+	EcdsaP256                     // ECDSA signatures on the P-256 curve
+	EcdsaP284                     // ECDSA signatures on the P-384 curve
+	EcdsaP521                     // ECDSA signatures on the P-521 curve
+	Ed25519                       // Ed25519 signatures
 )
 
 type dummyLoadCmd struct {
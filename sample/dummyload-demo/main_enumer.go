@@ -29,34 +29,40 @@ var _ enum.Type = (*WorkloadType)(nil)
 
 var WorkloadTypeValues = []enum.Value{
 	{
-		Name:     "rsa2048",
-		AltNames: []string{"rsa2048", "Rsa2048"},
-		Value:    Rsa2048,
+		Name:        "rsa2048",
+		AltNames:    []string{"rsa2048", "Rsa2048"},
+		Value:       Rsa2048,
+		Description: "RSA signatures with 2048-bit keys",
 	},
 	{
-		Name:     "rsa4096",
-		AltNames: []string{"rsa4096", "Rsa4096"},
-		Value:    Rsa4096,
+		Name:        "rsa4096",
+		AltNames:    []string{"rsa4096", "Rsa4096"},
+		Value:       Rsa4096,
+		Description: "RSA signatures with 4096-bit keys",
 	},
 	{
-		Name:     "ecdsa-p256",
-		AltNames: []string{"ecdsa-p256", "EcdsaP256", "ecdsaP256", "ecdsa_p256"},
-		Value:    EcdsaP256,
+		Name:        "ecdsa-p256",
+		AltNames:    []string{"ecdsa-p256", "EcdsaP256", "ecdsaP256", "ecdsa_p256"},
+		Value:       EcdsaP256,
+		Description: "ECDSA signatures on the P-256 curve",
 	},
 	{
-		Name:     "ecdsa-p284",
-		AltNames: []string{"ecdsa-p284", "EcdsaP284", "ecdsaP284", "ecdsa_p284"},
-		Value:    EcdsaP284,
+		Name:        "ecdsa-p284",
+		AltNames:    []string{"ecdsa-p284", "EcdsaP284", "ecdsaP284", "ecdsa_p284"},
+		Value:       EcdsaP284,
+		Description: "ECDSA signatures on the P-384 curve",
 	},
 	{
-		Name:     "ecdsa-p521",
-		AltNames: []string{"ecdsa-p521", "EcdsaP521", "ecdsaP521", "ecdsa_p521"},
-		Value:    EcdsaP521,
+		Name:        "ecdsa-p521",
+		AltNames:    []string{"ecdsa-p521", "EcdsaP521", "ecdsaP521", "ecdsa_p521"},
+		Value:       EcdsaP521,
+		Description: "ECDSA signatures on the P-521 curve",
 	},
 	{
-		Name:     "ed25519",
-		AltNames: []string{"ed25519", "Ed25519"},
-		Value:    Ed25519,
+		Name:        "ed25519",
+		AltNames:    []string{"ed25519", "Ed25519"},
+		Value:       Ed25519,
+		Description: "Ed25519 signatures",
 	},
 }
 
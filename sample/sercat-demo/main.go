@@ -10,12 +10,14 @@ import (
 
 func main() {
 	cli.Run(&cli.Command{
-		Handler:     &sercatCmd{},
-		Description: "Open a serial port and print all traffic to standard output",
+		Handler:          &sercatCmd{},
+		Description:      "Open a serial port and print all traffic to standard output",
+		ConfigSearchPath: []string{"~/.sercat-demo.yaml"},
 	})
 }
 
 type sercatCmd struct {
+	Config   string  `opts:"-c, --config, config-file, name:file" desc:"configuration file to load"`
 	Port     *string `opts:"arg:1, name:port" desc:"name of the port to open"`
 	Baudrate *uint32 `opts:"-b, --baudrate"   desc:"baudrate to use for communication"`
 	Format   *string `opts:"-f, --format"     desc:"communcation format, e.g. 8N1"`
@@ -55,8 +57,9 @@ func (options *sercatCmd) Complete(opt *option.T, partial string) []string {
 
 func (options *sercatCmd) Run() error {
 
-	// The following options use pointer type to allow getting fallback values
-	// from a configuration file if not set. Manually set defaults here.
+	// The following options use pointer type to detect when they are not set
+	// from any source, including the configuration file. Manually set defaults
+	// here.
 	if options.Baudrate == nil {
 		var v uint32 = 115200
 		options.Baudrate = &v